@@ -0,0 +1,126 @@
+package multipartreader
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/textproto"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Encoder transcodes UTF-8 text into a legacy charset.
+// Only single-byte charsets are built in, multi-byte ones like Shift_JIS
+// can be plugged in e.g. with an adapter of golang.org/x/text/encoding
+type Encoder interface {
+	// Charset returns name used in charset parameter and _charset_ field
+	Charset() string
+	Encode(s string) ([]byte, error)
+}
+
+// EncodeError is returned by Encoder when text has character not present in charset
+type EncodeError struct {
+	Charset string
+	Rune    rune
+}
+
+func (e *EncodeError) Error() string {
+	return fmt.Sprintf("multipartreader: %q can't be encoded in %s", e.Rune, e.Charset)
+}
+
+// Single-byte encoders with pure-Go tables, there are no built-in multi-byte ones
+var (
+	Windows1251 Encoder = &charmap{name: "windows-1251", high: &windows1251High}
+	Windows1252 Encoder = &charmap{name: "windows-1252", high: &windows1252High}
+	KOI8R       Encoder = &charmap{name: "KOI8-R", high: &koi8rHigh}
+	ISO88595    Encoder = &charmap{name: "ISO-8859-5", high: &iso88595High}
+	ISO88591    Encoder = &charmap{name: "ISO-8859-1"}
+)
+
+// charmap is single-byte charset which is ASCII in its low half,
+// nil high means identity mapping (ISO-8859-1)
+type charmap struct {
+	name string
+	high *[128]rune
+
+	once    sync.Once
+	reverse map[rune]byte
+}
+
+func (c *charmap) Charset() string {
+	return c.name
+}
+
+func (c *charmap) Encode(s string) ([]byte, error) {
+	c.once.Do(func() {
+		if c.high == nil {
+			return
+		}
+		c.reverse = make(map[rune]byte, 128)
+		for i, r := range c.high {
+			if r != 0 {
+				c.reverse[r] = byte(0x80 + i)
+			}
+		}
+	})
+
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch b, ok := c.reverse[r]; {
+		case r < 0x80:
+			out = append(out, byte(r))
+		case c.high == nil && r < 0x100:
+			out = append(out, byte(r))
+		case ok:
+			out = append(out, b)
+		default:
+			return nil, &EncodeError{Charset: c.name, Rune: r}
+		}
+	}
+	return out, nil
+}
+
+// WriteFieldsCharset writes form fields encoded with e, e may be nil to keep UTF-8.
+// Every field has Content-Type: text/plain with charset parameter, and
+// _charset_ field naming the charset is written before the first of them
+func (mr *MultipartReader) WriteFieldsCharset(fields map[string]string, e Encoder) error {
+	charset := "UTF-8"
+	if e != nil {
+		charset = e.Charset()
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	encoded := make([][]byte, len(keys))
+	for i, key := range keys {
+		if e == nil {
+			encoded[i] = []byte(fields[key])
+			continue
+		}
+		value, err := e.Encode(fields[key])
+		if err != nil {
+			return fmt.Errorf("field %q: %w", key, err)
+		}
+		encoded[i] = value
+	}
+
+	if !mr.charsetField {
+		mr.charsetField = true
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", `form-data; name="_charset_"`)
+		mr.AddPart(header, strings.NewReader(charset))
+	}
+	contentType := mime.FormatMediaType("text/plain", map[string]string{"charset": charset})
+	for i, key := range keys {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", formatDisposition("form-data", map[string]string{"name": key}))
+		header.Set("Content-Type", contentType)
+		mr.AddPart(header, bytes.NewReader(encoded[i]))
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package multipartreader
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime/multipart"
+	"testing"
+)
+
+// decodeCharmap decodes bytes encoded with charmap c
+func decodeCharmap(c *charmap, b []byte) string {
+	runes := make([]rune, len(b))
+	for i, x := range b {
+		switch {
+		case x < 0x80 || c.high == nil:
+			runes[i] = rune(x)
+		default:
+			runes[i] = c.high[x-0x80]
+		}
+	}
+	return string(runes)
+}
+
+func TestCharmapRoundTrip(t *testing.T) {
+	tests := []struct {
+		e    Encoder
+		text string
+		want string // hex encoded
+	}{
+		{Windows1251, "Привет, мир! Ёё №", "cff0e8e2e5f22c20ece8f02120a8b820b9"},
+		{KOI8R, "Привет, мир! Ёё", "f0d2c9d7c5d42c20cdc9d22120b3a3"},
+	}
+	for _, tt := range tests {
+		got, err := tt.e.Encode(tt.text)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.e.Charset(), err)
+		}
+		if hex.EncodeToString(got) != tt.want {
+			t.Errorf("%s: encoded to %x, want %s", tt.e.Charset(), got, tt.want)
+		}
+		if back := decodeCharmap(tt.e.(*charmap), got); back != tt.text {
+			t.Errorf("%s: decoded to %q, want %q", tt.e.Charset(), back, tt.text)
+		}
+	}
+
+	// every mapped character of every table
+	for _, e := range []Encoder{Windows1251, Windows1252, KOI8R, ISO88595, ISO88591} {
+		c := e.(*charmap)
+		var all []rune
+		for i := 0; i < 0x100; i++ {
+			switch {
+			case i < 0x80 || c.high == nil:
+				all = append(all, rune(i))
+			case c.high[i-0x80] != 0:
+				all = append(all, c.high[i-0x80])
+			}
+		}
+		encoded, err := e.Encode(string(all))
+		if err != nil {
+			t.Fatalf("%s: %v", e.Charset(), err)
+		}
+		if back := decodeCharmap(c, encoded); back != string(all) {
+			t.Errorf("%s: round trip differs", e.Charset())
+		}
+	}
+}
+
+func TestCharmapEncodeError(t *testing.T) {
+	tests := []struct {
+		e    Encoder
+		text string
+		r    rune
+	}{
+		{Windows1251, "日本", '日'},
+		{KOI8R, "номер №", '№'},
+		{ISO88591, "euro €", '€'},
+	}
+	for _, tt := range tests {
+		_, err := tt.e.Encode(tt.text)
+		var ee *EncodeError
+		if !errors.As(err, &ee) || ee.Rune != tt.r || ee.Charset != tt.e.Charset() {
+			t.Errorf("%s: got %v, want EncodeError of %q", tt.e.Charset(), err, tt.r)
+		}
+	}
+
+	mr := New()
+	err := mr.WriteFieldsCharset(map[string]string{"a": "ok", "b": "日本"}, Windows1251)
+	var ee *EncodeError
+	if !errors.As(err, &ee) {
+		t.Fatalf("got %v, want EncodeError", err)
+	}
+	if len(mr.parts) != 0 {
+		t.Errorf("%d parts added by failed WriteFieldsCharset", len(mr.parts))
+	}
+}
+
+func TestWriteFieldsCharset(t *testing.T) {
+	mr := New()
+	if err := mr.WriteFieldsCharset(map[string]string{"name": "Иван", `q"uote`: "Ёж"}, Windows1251); err != nil {
+		t.Fatal(err)
+	}
+	if err := mr.WriteFieldsCharset(map[string]string{"city": "Москва"}, Windows1251); err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(mr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct{ name, value string }{
+		{"_charset_", "windows-1251"},
+		{"name", "Иван"},
+		{`q"uote`, "Ёж"},
+		{"city", "Москва"},
+	}
+	r := multipart.NewReader(bytes.NewReader(body), mr.Boundary())
+	for i, w := range want {
+		p, err := r.NextPart()
+		if err != nil {
+			t.Fatalf("part %d: %v", i, err)
+		}
+		value, _ := io.ReadAll(p)
+		if p.FormName() != w.name {
+			t.Errorf("part %d is named %q, want %q", i, p.FormName(), w.name)
+		}
+		if i == 0 {
+			if string(value) != w.value {
+				t.Errorf("_charset_ is %q", value)
+			}
+			continue
+		}
+		if ct := p.Header.Get("Content-Type"); ct != "text/plain; charset=windows-1251" {
+			t.Errorf("part %d has type %q", i, ct)
+		}
+		if got := decodeCharmap(Windows1251.(*charmap), value); got != w.value {
+			t.Errorf("part %d is %q, want %q", i, got, w.value)
+		}
+	}
+	if _, err = r.NextPart(); err != io.EOF {
+		t.Errorf("got %v after the last part, want io.EOF", err)
+	}
+}
@@ -0,0 +1,113 @@
+package multipartreader
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/textproto"
+	"os"
+	"strings"
+)
+
+// SetNormalizeLineEndings enables normalization of bare LF and CR to CRLF
+// in text fields and text/* parts (RFC 7578), applies to parts added afterwards
+func (mr *MultipartReader) SetNormalizeLineEndings(on bool) {
+	mr.normalizeEOL = on
+}
+
+// isText reports if part with header is a text field or text/* part
+func isText(header textproto.MIMEHeader) bool {
+	if ct := header.Get("Content-Type"); ct != "" {
+		mt, _, err := mime.ParseMediaType(ct)
+		return err == nil && strings.HasPrefix(mt, "text/")
+	}
+	_, params, err := mime.ParseMediaType(header.Get("Content-Disposition"))
+	if err != nil {
+		return false
+	}
+	_, file := params["filename"]
+	return !file
+}
+
+// crlfReader normalizes line endings of r to CRLF while reading
+type crlfReader struct {
+	r      io.Reader
+	prevCR bool
+	chunk  []byte
+	buf    []byte
+	err    error
+}
+
+// normalizeCRLF appends src with normalized line endings to dst,
+// prevCR is the state between calls
+func normalizeCRLF(dst, src []byte, prevCR *bool) []byte {
+	for _, c := range src {
+		switch {
+		case c == '\r':
+			dst = append(dst, '\r', '\n')
+			*prevCR = true
+			continue
+		case c == '\n' && *prevCR:
+			// CRLF, already written
+		case c == '\n':
+			dst = append(dst, '\r', '\n')
+		default:
+			dst = append(dst, c)
+		}
+		*prevCR = false
+	}
+	return dst
+}
+
+func (cr *crlfReader) Read(p []byte) (n int, err error) {
+	if cr.chunk == nil {
+		cr.chunk = make([]byte, 32*1024)
+	}
+	for len(cr.buf) == 0 && cr.err == nil {
+		var rn int
+		rn, cr.err = cr.r.Read(cr.chunk)
+		cr.buf = normalizeCRLF(cr.buf[:0], cr.chunk[:rn], &cr.prevCR)
+	}
+	n = copy(p, cr.buf)
+	cr.buf = cr.buf[n:]
+	if len(cr.buf) == 0 && n == 0 {
+		return 0, cr.err
+	}
+	return n, nil
+}
+
+// size returns length of normalized content by scanning the source,
+// without moving its position
+func (cr *crlfReader) size() (int64, bool) {
+	var src io.Reader
+	switch r := cr.r.(type) {
+	case *strings.Reader:
+		src = io.NewSectionReader(r, r.Size()-int64(r.Len()), int64(r.Len()))
+	case *bytes.Reader:
+		src = io.NewSectionReader(r, r.Size()-int64(r.Len()), int64(r.Len()))
+	case *fileSource:
+		f, err := os.Open(r.path)
+		if err != nil {
+			return 0, false
+		}
+		defer f.Close()
+		src = io.NewSectionReader(f, r.pos, r.size-r.pos)
+	default:
+		return 0, false
+	}
+
+	var n int64
+	prevCR := cr.prevCR
+	chunk := make([]byte, 32*1024)
+	out := make([]byte, 0, 64*1024)
+	for {
+		rn, err := src.Read(chunk)
+		n += int64(len(normalizeCRLF(out[:0], chunk[:rn], &prevCR)))
+		if err == io.EOF {
+			return n + int64(len(cr.buf)), true
+		}
+		if err != nil {
+			return 0, false
+		}
+	}
+}
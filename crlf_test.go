@@ -0,0 +1,66 @@
+package multipartreader
+
+import (
+	"io"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestNormalizeLineEndings(t *testing.T) {
+	const (
+		text       = "a\nb\rc\r\nd\n\re"
+		normalized = "a\r\nb\r\nc\r\nd\r\n\r\ne"
+	)
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte(text), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fs, err := newFileSource(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mr := New()
+	mr.SetNormalizeLineEndings(true)
+	mr.SetPartContentLength(PartLengthKnown)
+	mr.WriteFields(map[string]string{"field": text})
+	mr.AddPart(textproto.MIMEHeader{
+		"Content-Disposition": {`form-data; name="notes"; filename="notes.txt"`},
+		"Content-Type":        {"text/plain"},
+	}, fs)
+	mr.AddFormReader("binary", "data.bin", strings.NewReader(text))
+	size, ok := mr.Len()
+
+	parts := readParts(t, mr)
+	for i, want := range []string{normalized, normalized, text} {
+		if parts[i].Body != want {
+			t.Errorf("part %d is %q, want %q", i, parts[i].Body, want)
+		}
+		if cl := parts[i].Header.Get("Content-Length"); cl != "" && cl != strconv.Itoa(len(want)) {
+			t.Errorf("part %d has Content-Length %s, content is %d bytes", i, cl, len(want))
+		}
+	}
+	if !ok || size != mr.Count() {
+		t.Errorf("Len is %d, %v, read %d bytes", size, ok, mr.Count())
+	}
+}
+
+func TestCRLFReaderSplitCR(t *testing.T) {
+	// CR and LF of one line ending arrive in different reads
+	cr := &crlfReader{r: iotest.OneByteReader(strings.NewReader("a\r\nb\r\rc\n"))}
+	got, err := io.ReadAll(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a\r\nb\r\n\r\nc\r\n"; string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if _, ok := cr.size(); ok {
+		t.Error("size of unknown source is known")
+	}
+}
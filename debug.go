@@ -0,0 +1,96 @@
+package multipartreader
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+)
+
+// DebugOptions configures debug tap set with SetDebug
+type DebugOptions struct {
+	// MaxBody is number of bytes of each part body written to the tap
+	MaxBody int
+	// Redact lists field names whose bodies are replaced with [REDACTED]
+	Redact []string
+}
+
+// debugTap copies emitted bytes to w, headers fully and bodies truncated
+type debugTap struct {
+	w    io.Writer
+	opts DebugOptions
+}
+
+// SetDebug copies emitted bytes to w while reading, for diagnosing interop problems.
+// Must be called before the first Read
+func (mr *MultipartReader) SetDebug(w io.Writer, opts DebugOptions) {
+	mr.debug = &debugTap{w: w, opts: opts}
+}
+
+// wrap wraps readers of MultipartReader, part bodies are truncated or redacted
+func (d *debugTap) wrap(readers []io.Reader) []io.Reader {
+	wrapped := make([]io.Reader, len(readers))
+	for i, r := range readers {
+		if _, ok := r.(*proxyReader); ok {
+			// proxied parts are wrapped when they are pulled
+			wrapped[i] = r
+			continue
+		}
+		if pr, ok := r.(*partReader); ok {
+			wrapped[i] = &debugBodyReader{r: pr, d: d, redact: d.redacted(pr.p)}
+			continue
+		}
+		wrapped[i] = io.TeeReader(r, d.w)
+	}
+	return wrapped
+}
+
+// redacted reports if body of p must not be shown
+func (d *debugTap) redacted(p *part) bool {
+	_, params, err := mime.ParseMediaType(p.header.Get("Content-Disposition"))
+	if err != nil {
+		return false
+	}
+	for _, name := range d.opts.Redact {
+		if strings.EqualFold(name, params["name"]) {
+			return true
+		}
+	}
+	return false
+}
+
+// debugBodyReader writes up to MaxBody bytes of part body to debug tap
+type debugBodyReader struct {
+	r       io.Reader
+	d       *debugTap
+	redact  bool
+	written int
+	skipped int64
+}
+
+func (dr *debugBodyReader) Read(p []byte) (n int, err error) {
+	n, err = dr.r.Read(p)
+	chunk := p[:n]
+	if !dr.redact {
+		if left := dr.d.opts.MaxBody - dr.written; left < len(chunk) {
+			if left < 0 {
+				left = 0
+			}
+			dr.skipped += int64(len(chunk) - left)
+			chunk = chunk[:left]
+		}
+		dr.d.w.Write(chunk)
+		dr.written += len(chunk)
+	} else {
+		dr.skipped += int64(n)
+	}
+	if err == io.EOF {
+		switch {
+		case dr.redact:
+			fmt.Fprintf(dr.d.w, "[REDACTED %d bytes]", dr.skipped)
+		case dr.skipped > 0:
+			fmt.Fprintf(dr.d.w, "[... %d bytes truncated]", dr.skipped)
+		}
+	}
+	return
+}
@@ -0,0 +1,526 @@
+package multipartreader
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/textproto"
+	"strings"
+)
+
+var (
+	// ErrNoBoundary is returned by NewDecoder when boundary is unknown
+	ErrNoBoundary = errors.New("multipartreader: no boundary")
+	// ErrMalformed is returned by Decoder for bodies it can't parse
+	ErrMalformed = errors.New("multipartreader: malformed multipart body")
+)
+
+// AnomalyKind is kind of deviation from RFC 2046 tolerated by lenient Decoder
+type AnomalyKind int
+
+const (
+	// AnomalySniffedBoundary means boundary was discovered from the body
+	AnomalySniffedBoundary AnomalyKind = iota + 1
+	// AnomalyLFLineEnding means line ended with bare LF instead of CRLF
+	AnomalyLFLineEnding
+	// AnomalyTransportPadding means whitespace after boundary
+	AnomalyTransportPadding
+	// AnomalyMissingFinalDelimiter means body ended without close delimiter
+	AnomalyMissingFinalDelimiter
+)
+
+func (k AnomalyKind) String() string {
+	switch k {
+	case AnomalySniffedBoundary:
+		return "sniffed boundary"
+	case AnomalyLFLineEnding:
+		return "LF line ending"
+	case AnomalyTransportPadding:
+		return "transport padding"
+	case AnomalyMissingFinalDelimiter:
+		return "missing final delimiter"
+	}
+	return fmt.Sprintf("AnomalyKind(%d)", int(k))
+}
+
+// Anomaly is deviation from RFC 2046 found by Decoder, Part is -1 outside of parts
+type Anomaly struct {
+	Kind AnomalyKind
+	Part int
+}
+
+// DecoderOptions configures Decoder
+type DecoderOptions struct {
+	// SniffBoundary discovers boundary from the first delimiter line
+	// when Content-Type is empty, e.g. for saved bodies and raw captures
+	SniffBoundary bool
+	// Lenient accepts LF-only line endings and missing final delimiter,
+	// found deviations are reported by Anomalies
+	Lenient bool
+	// Limits protect the decoder from hostile bodies
+	Limits Limits
+}
+
+// Decoder reads parts of multipart body, it is the decode side of MultipartReader
+type Decoder struct {
+	r        *bufio.Reader
+	src      *countingReader
+	boundary string
+	opts     DecoderOptions
+
+	current   *Part
+	parts     int
+	started   bool
+	done      bool
+	anomalies []Anomaly
+}
+
+// Part is a single part read by Decoder
+type Part struct {
+	Header textproto.MIMEHeader
+	Index  int
+
+	d             *Decoder
+	headerOffset  int64
+	contentOffset int64
+	n             int // content bytes which can be read from buffer
+	size          int64
+	max           int64 // size limit, 0 means unlimited
+	limit         string
+	found         bool // delimiter follows n bytes
+	eof           bool
+	readErr       error
+}
+
+// NewDecoder creates new Decoder, boundary is taken from contentType.
+// contentType may be empty with SniffBoundary option
+func NewDecoder(r io.Reader, contentType string, opts DecoderOptions) (*Decoder, error) {
+	src := &countingReader{r: r, limits: opts.Limits}
+	d := &Decoder{r: bufio.NewReaderSize(src, 64*1024), src: src, opts: opts}
+	if contentType != "" {
+		mediaType, params, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(mediaType, "multipart/") {
+			return nil, fmt.Errorf("%w: %s is not multipart", ErrNoBoundary, mediaType)
+		}
+		d.boundary = params["boundary"]
+	}
+	if d.boundary == "" && !opts.SniffBoundary {
+		return nil, ErrNoBoundary
+	}
+	return d, nil
+}
+
+// Boundary returns boundary of the body, it is known after the first NextPart when sniffed
+func (d *Decoder) Boundary() string {
+	return d.boundary
+}
+
+// Anomalies returns deviations from RFC 2046 found so far
+func (d *Decoder) Anomalies() []Anomaly {
+	return d.anomalies
+}
+
+func (d *Decoder) anomaly(kind AnomalyKind, part int) {
+	if n := len(d.anomalies); n > 0 && d.anomalies[n-1] == (Anomaly{kind, part}) {
+		return
+	}
+	d.anomalies = append(d.anomalies, Anomaly{Kind: kind, Part: part})
+}
+
+// NextPart returns next part of the body, previous part is drained.
+// Returns io.EOF after the last part
+func (d *Decoder) NextPart() (p *Part, err error) {
+	if d.current != nil {
+		if _, err = io.Copy(io.Discard, d.current); err != nil {
+			return
+		}
+		d.current = nil
+	}
+	if d.done {
+		return nil, io.EOF
+	}
+
+	if !d.started {
+		d.started = true
+		if err = d.skipPreamble(); err != nil {
+			return
+		}
+	} else if err = d.readDelimiter(); err != nil {
+		return
+	}
+	if d.done {
+		return nil, io.EOF
+	}
+
+	if max := d.opts.Limits.MaxParts; max > 0 && d.parts >= max {
+		return nil, &LimitError{Limit: "MaxParts", Value: int64(max), Part: d.parts}
+	}
+	p = &Part{Index: d.parts, d: d, headerOffset: d.offset()}
+	if p.Header, err = d.readHeader(p.Index); err != nil {
+		if err == io.EOF && d.opts.Lenient {
+			d.anomaly(AnomalyMissingFinalDelimiter, -1)
+			d.done = true
+		}
+		return nil, err
+	}
+	p.contentOffset = d.offset()
+	p.max, p.limit = d.opts.Limits.partMax(p)
+	d.parts++
+	d.current = p
+	return
+}
+
+// offset returns number of body bytes consumed by Decoder
+func (d *Decoder) offset() int64 {
+	return d.src.n - int64(d.r.Buffered())
+}
+
+// maxLine is limit of delimiter and preamble lines kept in memory
+const maxLine = 4096
+
+// readLine reads the whole line, at most max bytes of it are kept
+// and long is set when the line was longer
+func (d *Decoder) readLine(max int) (line []byte, long bool, err error) {
+	for {
+		chunk, rerr := d.r.ReadSlice('\n')
+		if len(line)+len(chunk) > max {
+			long = true
+			chunk = chunk[:max-len(line)]
+		}
+		line = append(line, chunk...)
+		if rerr != bufio.ErrBufferFull {
+			return line, long, rerr
+		}
+	}
+}
+
+// trimEOL trims line ending, LF-only ending is error unless decoder is lenient
+func (d *Decoder) trimEOL(line []byte, part int) ([]byte, error) {
+	switch {
+	case bytes.HasSuffix(line, []byte("\r\n")):
+		return line[:len(line)-2], nil
+	case bytes.HasSuffix(line, []byte("\n")):
+		if !d.opts.Lenient {
+			return nil, fmt.Errorf("%w: LF line ending", ErrMalformed)
+		}
+		d.anomaly(AnomalyLFLineEnding, part)
+		return line[:len(line)-1], nil
+	}
+	return line, nil
+}
+
+// skipPreamble skips lines before the first delimiter, boundary is sniffed
+// from the first line which looks like a delimiter when unknown
+func (d *Decoder) skipPreamble() error {
+	for {
+		line, _, err := d.readLine(maxLine)
+		if len(line) == 0 && err != nil {
+			if err == io.EOF {
+				return fmt.Errorf("%w: no delimiter found", ErrMalformed)
+			}
+			return err
+		}
+		if bytes.HasPrefix(line, []byte("--")) && d.boundary == "" {
+			b := strings.TrimRight(string(line[2:]), " \t\r\n")
+			if b != "" && len(b) <= 70 && !strings.HasSuffix(b, "--") {
+				d.boundary = b
+				d.anomaly(AnomalySniffedBoundary, -1)
+			}
+		}
+		if d.boundary != "" {
+			if ok, perr := d.parseDelimiter(line, -1); ok || perr != nil {
+				return perr
+			}
+		}
+		if err == io.EOF {
+			return fmt.Errorf("%w: no delimiter found", ErrMalformed)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// readDelimiter reads delimiter line which ends the current part
+func (d *Decoder) readDelimiter() error {
+	// line ending which precedes delimiter belongs to it
+	nl, _, err := d.readLine(maxLine)
+	if err != nil {
+		return err
+	}
+	if _, err = d.trimEOL(nl, d.parts-1); err != nil {
+		return err
+	}
+	line, _, err := d.readLine(maxLine)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	ok, err := d.parseDelimiter(line, d.parts-1)
+	if err == nil && !ok {
+		err = fmt.Errorf("%w: delimiter expected", ErrMalformed)
+	}
+	return err
+}
+
+// parseDelimiter checks if line is delimiter, done is set for close delimiter
+func (d *Decoder) parseDelimiter(line []byte, part int) (ok bool, err error) {
+	dash := "--" + d.boundary
+	if !bytes.HasPrefix(line, []byte(dash)) {
+		return false, nil
+	}
+	rest := line[len(dash):]
+	final := bytes.HasPrefix(rest, []byte("--"))
+	if final {
+		rest = rest[2:]
+	}
+
+	hasEOL := bytes.HasSuffix(rest, []byte("\n"))
+	if !hasEOL && !final {
+		// body ends right after delimiter
+		if !d.opts.Lenient {
+			return false, fmt.Errorf("%w: unexpected end of body", ErrMalformed)
+		}
+		d.anomaly(AnomalyMissingFinalDelimiter, part)
+		final = true
+	}
+	if rest, err = d.trimEOL(rest, part); err != nil {
+		return false, err
+	}
+	if len(rest) > 0 {
+		if len(bytes.TrimLeft(rest, " \t")) > 0 {
+			// longer line which only starts with the boundary
+			return false, nil
+		}
+		d.anomaly(AnomalyTransportPadding, part)
+	}
+	d.done = final
+	return true, nil
+}
+
+// readHeader reads MIME header of part, folded lines are unfolded
+func (d *Decoder) readHeader(part int) (textproto.MIMEHeader, error) {
+	header := textproto.MIMEHeader{}
+	var key, value string
+	flush := func() {
+		if key != "" {
+			header.Add(key, strings.TrimSpace(value))
+		}
+		key, value = "", ""
+	}
+	limits := d.opts.Limits
+	max := maxHeaderBytes
+	if limits.MaxHeaderBytes > 0 {
+		max = limits.MaxHeaderBytes
+	}
+	budget, lines := max, 0
+	for {
+		line, long, err := d.readLine(budget)
+		if long {
+			return nil, &LimitError{Limit: "MaxHeaderBytes", Value: int64(max), Part: part}
+		}
+		budget -= len(line)
+		if err != nil {
+			if err == io.EOF && len(line) == 0 && len(header) == 0 && key == "" {
+				return nil, io.EOF
+			}
+			if err == io.EOF {
+				return nil, fmt.Errorf("%w: unexpected end of header", ErrMalformed)
+			}
+			return nil, err
+		}
+		if line, err = d.trimEOL(line, part); err != nil {
+			return nil, err
+		}
+		if len(line) == 0 {
+			flush()
+			return header, nil
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			if key == "" {
+				return nil, fmt.Errorf("%w: continuation line without header", ErrMalformed)
+			}
+			value += " " + strings.TrimSpace(string(line))
+			continue
+		}
+		flush()
+		if lines++; limits.MaxHeaders > 0 && lines > limits.MaxHeaders {
+			return nil, &LimitError{Limit: "MaxHeaders", Value: int64(limits.MaxHeaders), Part: part}
+		}
+		i := bytes.IndexByte(line, ':')
+		if i <= 0 {
+			return nil, fmt.Errorf("%w: malformed header line %q", ErrMalformed, line)
+		}
+		key = textproto.CanonicalMIMEHeaderKey(string(bytes.TrimRight(line[:i], " \t")))
+		value = string(line[i+1:])
+	}
+}
+
+// nlDelimiter is the sequence which ends content of a part
+func (d *Decoder) nlDelimiter() []byte {
+	if d.opts.Lenient {
+		return []byte("\n--" + d.boundary)
+	}
+	return []byte("\r\n--" + d.boundary)
+}
+
+// delimiter results of checkDelimiter
+const (
+	delimInvalid = iota
+	delimValid
+	delimNeedMore
+)
+
+// checkDelimiter checks bytes after boundary in a possible delimiter
+func (d *Decoder) checkDelimiter(rest []byte, atEOF bool) int {
+	if len(rest) == 0 {
+		if atEOF && d.opts.Lenient {
+			return delimValid
+		}
+		if atEOF {
+			return delimInvalid
+		}
+		return delimNeedMore
+	}
+	if rest[0] == '-' {
+		if len(rest) < 2 {
+			if atEOF {
+				return delimInvalid
+			}
+			return delimNeedMore
+		}
+		if rest[1] == '-' {
+			return delimValid
+		}
+		return delimInvalid
+	}
+	rest = bytes.TrimLeft(rest, " \t")
+	switch {
+	case len(rest) == 0 && atEOF:
+		if d.opts.Lenient {
+			return delimValid
+		}
+		return delimInvalid
+	case len(rest) == 0:
+		return delimNeedMore
+	case rest[0] == '\n':
+		return delimValid
+	case rest[0] == '\r' && len(rest) == 1 && !atEOF:
+		return delimNeedMore
+	case rest[0] == '\r' && len(rest) > 1 && rest[1] == '\n':
+		return delimValid
+	}
+	return delimInvalid
+}
+
+// scan returns number of content bytes at the start of buf,
+// found is set when delimiter follows them
+func (d *Decoder) scan(buf []byte, atEOF bool) (n int, found bool) {
+	dl := d.nlDelimiter()
+	for from := 0; ; {
+		i := bytes.Index(buf[from:], dl)
+		if i < 0 {
+			break
+		}
+		i += from
+		end := i
+		if d.opts.Lenient && i > 0 && buf[i-1] == '\r' {
+			end = i - 1
+		}
+		switch d.checkDelimiter(buf[i+len(dl):], atEOF) {
+		case delimValid:
+			return end, true
+		case delimNeedMore:
+			return end, false
+		}
+		from = i + 1
+	}
+	if atEOF {
+		return len(buf), false
+	}
+	// keep possible beginning of delimiter in buffer
+	n = len(buf) - len(dl) - 1
+	if n < 0 {
+		n = 0
+	}
+	return n, false
+}
+
+func (p *Part) Read(b []byte) (n int, err error) {
+	d := p.d
+	for p.n == 0 && !p.found && !p.eof {
+		peek, _ := d.r.Peek(d.r.Buffered())
+		p.n, p.found = d.scan(peek, p.readErr == io.EOF)
+		if p.n > 0 || p.found {
+			break
+		}
+		if p.readErr != nil {
+			// no delimiter before the end of body
+			if p.readErr != io.EOF {
+				return 0, p.readErr
+			}
+			if !d.opts.Lenient {
+				return 0, fmt.Errorf("%w: %v", ErrMalformed, io.ErrUnexpectedEOF)
+			}
+			d.anomaly(AnomalyMissingFinalDelimiter, p.Index)
+			d.done = true
+			p.eof = true
+			break
+		}
+		_, p.readErr = d.r.Peek(len(peek) + 1)
+	}
+	if p.n == 0 {
+		p.eof = true
+		return 0, io.EOF
+	}
+	if p.max > 0 {
+		left := p.max - p.size
+		if left == 0 {
+			return 0, &LimitError{Limit: p.limit, Value: p.max, Part: p.Index}
+		}
+		if int64(len(b)) > left {
+			b = b[:left]
+		}
+	}
+	if len(b) > p.n {
+		b = b[:p.n]
+	}
+	n, err = d.r.Read(b)
+	p.n -= n
+	p.size += int64(n)
+	return n, err
+}
+
+// Close drains the rest of part content
+func (p *Part) Close() error {
+	_, err := io.Copy(io.Discard, p)
+	return err
+}
+
+// FormName returns name parameter of form-data Content-Disposition
+func (p *Part) FormName() string {
+	return p.dispositionParam("name")
+}
+
+// FileName returns filename parameter of Content-Disposition as sent by client
+func (p *Part) FileName() string {
+	return p.dispositionParam("filename")
+}
+
+func (p *Part) dispositionParam(key string) string {
+	return dispositionParam(p.Header, key)
+}
+
+// dispositionParam returns parameter of Content-Disposition
+func dispositionParam(header textproto.MIMEHeader, key string) string {
+	_, params, err := mime.ParseMediaType(header.Get("Content-Disposition"))
+	if err != nil {
+		return ""
+	}
+	return params[key]
+}
@@ -0,0 +1,234 @@
+package multipartreader
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// decodeContents decodes body and returns contents of its parts
+func decodeContents(r io.Reader, contentType string, opts DecoderOptions) (contents []string, anomalies []Anomaly, err error) {
+	d, err := NewDecoder(r, contentType, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	for {
+		p, err := d.NextPart()
+		if err == io.EOF {
+			return contents, d.Anomalies(), nil
+		}
+		if err != nil {
+			return contents, d.Anomalies(), err
+		}
+		b, err := io.ReadAll(p)
+		contents = append(contents, string(b))
+		if err != nil {
+			return contents, d.Anomalies(), err
+		}
+	}
+}
+
+func TestDecoderAnomalies(t *testing.T) {
+	const ct = "multipart/form-data; boundary=b"
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		contents    []string
+		anomalies   []Anomaly
+		strictErr   bool // strict decoder fails, lenient one reports anomalies
+	}{
+		{
+			name:     "valid",
+			body:     "preamble\r\n--b\r\nA: 1\r\n\r\nx\r\n--b\r\n\r\ny\r\n--b--\r\nepilogue",
+			contents: []string{"x", "y"},
+		},
+		{
+			name:        "sniffed boundary",
+			body:        "--b\r\nA: 1\r\n\r\nx\r\n--b--\r\n",
+			contentType: "-",
+			contents:    []string{"x"},
+			anomalies:   []Anomaly{{AnomalySniffedBoundary, -1}},
+		},
+		{
+			name:      "LF line ending",
+			body:      "--b\nA: 1\n\nx\n--b--\n",
+			contents:  []string{"x"},
+			anomalies: []Anomaly{{AnomalyLFLineEnding, -1}, {AnomalyLFLineEnding, 0}},
+			strictErr: true,
+		},
+		{
+			name:      "transport padding",
+			body:      "--b \t\r\nA: 1\r\n\r\nx\r\n--b-- \r\n",
+			contents:  []string{"x"},
+			anomalies: []Anomaly{{AnomalyTransportPadding, -1}, {AnomalyTransportPadding, 0}},
+		},
+		{
+			name:      "missing final delimiter",
+			body:      "--b\r\nA: 1\r\n\r\nx",
+			contents:  []string{"x"},
+			anomalies: []Anomaly{{AnomalyMissingFinalDelimiter, 0}},
+			strictErr: true,
+		},
+		{
+			name:      "body ends with delimiter",
+			body:      "--b\r\nA: 1\r\n\r\nx\r\n--b",
+			contents:  []string{"x"},
+			anomalies: []Anomaly{{AnomalyMissingFinalDelimiter, 0}},
+			strictErr: true,
+		},
+	}
+	for _, tt := range tests {
+		contentType := ct
+		if tt.contentType == "-" {
+			contentType = ""
+		}
+		for _, lenient := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/lenient=%v", tt.name, lenient), func(t *testing.T) {
+				opts := DecoderOptions{SniffBoundary: true, Lenient: lenient}
+				contents, anomalies, err := decodeContents(strings.NewReader(tt.body), contentType, opts)
+				if tt.strictErr && !lenient {
+					if !errors.Is(err, ErrMalformed) {
+						t.Fatalf("got %v, want ErrMalformed", err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(contents, tt.contents) {
+					t.Errorf("contents are %q, want %q", contents, tt.contents)
+				}
+				if !reflect.DeepEqual(anomalies, tt.anomalies) {
+					t.Errorf("anomalies are %v, want %v", anomalies, tt.anomalies)
+				}
+			})
+		}
+	}
+}
+
+func TestDecoderMalformed(t *testing.T) {
+	for _, body := range []string{
+		"",
+		"no delimiter at all",
+		"--b\r\nbad header\r\n\r\nx\r\n--b--\r\n",
+		"--b\r\n continuation\r\n\r\nx\r\n--b--\r\n",
+		"--b\r\nA: 1\r\n",
+		"--b\r\nA: 1\r\n\r\nx\r\n--bb\r\n",
+	} {
+		if _, _, err := decodeContents(strings.NewReader(body), "multipart/mixed; boundary=b", DecoderOptions{}); !errors.Is(err, ErrMalformed) {
+			t.Errorf("%q: got %v, want ErrMalformed", body, err)
+		}
+	}
+	if _, err := NewDecoder(strings.NewReader(""), "text/plain", DecoderOptions{}); !errors.Is(err, ErrNoBoundary) {
+		t.Errorf("got %v, want ErrNoBoundary", err)
+	}
+}
+
+// roundTripContents are part contents which look like delimiters or cross buffer refills
+func roundTripContents() []string {
+	contents := []string{
+		"",
+		"x",
+		"\r\n",
+		"--",
+		"line\r\n--not the boundary",
+		"ends with CR\r",
+		"ends with CRLF\r\n",
+		strings.Repeat("a", 64*1024-1),
+		strings.Repeat("b", 64*1024+17),
+	}
+	// delimiter starts at every position around the end of the first buffer
+	for n := 64*1024 - 80; n < 64*1024+8; n += 7 {
+		contents = append(contents, strings.Repeat("c", n))
+	}
+	return contents
+}
+
+func TestDecoderRoundTrip(t *testing.T) {
+	contents := roundTripContents()
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for i, c := range contents {
+		fw, _ := w.CreateFormField(fmt.Sprintf("f%d", i))
+		fw.Write([]byte(c))
+	}
+	w.Close()
+
+	readers := map[string]func() io.Reader{
+		"whole":    func() io.Reader { return bytes.NewReader(buf.Bytes()) },
+		"one byte": func() io.Reader { return iotest.OneByteReader(bytes.NewReader(buf.Bytes())) },
+		"half":     func() io.Reader { return iotest.HalfReader(bytes.NewReader(buf.Bytes())) },
+	}
+	for name, r := range readers {
+		for _, lenient := range []bool{false, true} {
+			got, anomalies, err := decodeContents(r(), w.FormDataContentType(), DecoderOptions{Lenient: lenient})
+			if err != nil {
+				t.Fatalf("%s lenient=%v: %v", name, lenient, err)
+			}
+			if !reflect.DeepEqual(got, contents) {
+				t.Errorf("%s lenient=%v: contents differ", name, lenient)
+			}
+			if len(anomalies) > 0 {
+				t.Errorf("%s lenient=%v: anomalies %v", name, lenient, anomalies)
+			}
+		}
+	}
+}
+
+func TestDecoderReadsMultipartReader(t *testing.T) {
+	contents := roundTripContents()
+	mr := New()
+	for i, c := range contents {
+		mr.WriteFields(map[string]string{fmt.Sprintf("f%03d", i): c})
+	}
+	body, err := io.ReadAll(mr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, _, err := decodeContents(bytes.NewReader(body), mr.ContentType(), DecoderOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, contents) {
+		t.Errorf("Decoder contents differ")
+	}
+
+	parts := parseParts(t, mr.ContentType(), body)
+	if len(parts) != len(contents) {
+		t.Fatalf("mime/multipart found %d parts, want %d", len(parts), len(contents))
+	}
+	for i, p := range parts {
+		if p.Body != contents[i] {
+			t.Errorf("mime/multipart part %d differs", i)
+		}
+	}
+}
+
+func TestDecoderHeaders(t *testing.T) {
+	body := "--b\r\nContent-Disposition: form-data;\r\n name=\"f\"; filename=\"a.txt\"\r\nX-Twice: 1\r\nx-twice: 2\r\n\r\nx\r\n--b--\r\n"
+	d, err := NewDecoder(strings.NewReader(body), "multipart/form-data; boundary=b", DecoderOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := d.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.FormName() != "f" || p.FileName() != "a.txt" {
+		t.Errorf("form name %q, filename %q", p.FormName(), p.FileName())
+	}
+	if got := p.Header["X-Twice"]; !reflect.DeepEqual(got, []string{"1", "2"}) {
+		t.Errorf("X-Twice is %q", got)
+	}
+	if _, err = d.NextPart(); err != io.EOF {
+		t.Errorf("got %v after the last part, want io.EOF", err)
+	}
+}
@@ -0,0 +1,202 @@
+package multipartreader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrDropped is returned for destination dropped by DropSlowest policy
+var ErrDropped = errors.New("multipartreader: destination dropped as too slow")
+
+// FanOutPolicy decides what to do when buffer of a destination is full
+type FanOutPolicy int
+
+const (
+	// WaitSlowest blocks reading until the slowest destination catches up
+	WaitSlowest FanOutPolicy = iota
+	// DropSlowest drops destination whose buffer stays full for DropTimeout
+	DropSlowest
+)
+
+// FanOutOptions configures FanOut
+type FanOutOptions struct {
+	// Client is used to send requests, http.DefaultClient if nil
+	Client *http.Client
+	// BufferSize is per destination buffer in bytes, 1MB if zero
+	BufferSize int
+	Policy     FanOutPolicy
+	// DropTimeout is used by DropSlowest, 5 seconds if zero
+	DropTimeout time.Duration
+}
+
+// FanOutResult is result of upload to a single destination
+type FanOutResult struct {
+	Request  *http.Request
+	Response *http.Response
+	Err      error
+}
+
+const fanOutChunk = 32 * 1024
+
+// fanOutDest is request body of a single destination fed by FanOut
+type fanOutDest struct {
+	ch   chan []byte
+	done chan struct{}
+	err  error
+	buf  []byte
+	// cancel aborts request of dropped destination,
+	// transport may be blocked writing to a server which does not read
+	cancel context.CancelFunc
+}
+
+func (d *fanOutDest) Read(p []byte) (n int, err error) {
+	if len(d.buf) == 0 {
+		b, ok := <-d.ch
+		if !ok {
+			if d.err != nil {
+				return 0, d.err
+			}
+			return 0, io.EOF
+		}
+		d.buf = b
+	}
+	n = copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return
+}
+
+func (d *fanOutDest) Close() error {
+	return nil
+}
+
+// cancelBody cancels request context when response body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// FanOut sends body of MultipartReader to every request at once,
+// each source is read only once. Request of dropped destination is canceled.
+// Results are in order of reqs, caller must close response bodies
+func (mr *MultipartReader) FanOut(reqs []*http.Request, opts FanOutOptions) []FanOutResult {
+	client := opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	size := opts.BufferSize
+	if size <= 0 {
+		size = 1 << 20
+	}
+	chunks := size / fanOutChunk
+	if chunks < 1 {
+		chunks = 1
+	}
+	timeout := opts.DropTimeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+
+	results := make([]FanOutResult, len(reqs))
+	dests := make([]*fanOutDest, len(reqs))
+	closed := make([]bool, len(reqs))
+
+	var wg sync.WaitGroup
+	for i, req := range reqs {
+		d := &fanOutDest{ch: make(chan []byte, chunks), done: make(chan struct{})}
+		dests[i] = d
+		var ctx context.Context
+		ctx, d.cancel = context.WithCancel(req.Context())
+		req = req.WithContext(ctx)
+		req.Body = d
+		req.ContentLength = -1
+		req.Header.Set("Content-Type", mr.contentType)
+
+		wg.Add(1)
+		go func(i int, req *http.Request) {
+			defer wg.Done()
+			defer close(d.done)
+			results[i].Request = req
+			results[i].Response, results[i].Err = client.Do(req)
+			if results[i].Err != nil {
+				d.cancel()
+				return
+			}
+			results[i].Response.Body = &cancelBody{ReadCloser: results[i].Response.Body, cancel: d.cancel}
+		}(i, req)
+	}
+
+	finish := func(i int, err error) {
+		if closed[i] {
+			return
+		}
+		closed[i] = true
+		dests[i].err = err
+		close(dests[i].ch)
+		if err == ErrDropped {
+			dests[i].cancel()
+		}
+	}
+
+	buf := make([]byte, fanOutChunk)
+	for {
+		n, err := mr.Read(buf)
+		if n > 0 {
+			for i, d := range dests {
+				if closed[i] {
+					continue
+				}
+				chunk := append([]byte(nil), buf[:n]...)
+				if opts.Policy == DropSlowest {
+					timer := time.NewTimer(timeout)
+					select {
+					case d.ch <- chunk:
+					case <-d.done:
+						closed[i] = true
+					case <-timer.C:
+						finish(i, ErrDropped)
+					}
+					timer.Stop()
+					continue
+				}
+				select {
+				case d.ch <- chunk:
+				case <-d.done:
+					closed[i] = true
+				}
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			for i := range dests {
+				finish(i, err)
+			}
+			break
+		}
+	}
+
+	wg.Wait()
+	for i, d := range dests {
+		if d.err == ErrDropped {
+			// request was canceled or server answered before noticing the truncated body
+			if results[i].Response != nil {
+				results[i].Response.Body.Close()
+				results[i].Response = nil
+			}
+			results[i].Err = ErrDropped
+		}
+		mr.logResult("multipart fan-out destination", results[i].Request, results[i].Response, results[i].Err)
+	}
+	return results
+}
@@ -0,0 +1,204 @@
+package multipartreader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newFanOutSource returns body of 1MB, the same for every call
+func newFanOutSource(t *testing.T) *MultipartReader {
+	t.Helper()
+	mr := New()
+	if err := mr.SetBoundary("fanout"); err != nil {
+		t.Fatal(err)
+	}
+	mr.WriteFields(map[string]string{"a": "1"})
+	mr.AddFormReader("file", "big.bin", strings.NewReader(strings.Repeat("0123456789abcdef", 64*1024)))
+	return mr
+}
+
+// fanOutServer is destination of FanOut, it records received body
+type fanOutServer struct {
+	*httptest.Server
+	mu   sync.Mutex
+	body []byte
+}
+
+// newFanOutServer starts destination which reads body with read and answers 200,
+// socket read buffer is set to buffer if not zero, so a slow destination really holds FanOut back
+func newFanOutServer(t *testing.T, buffer int, read func(r io.Reader) ([]byte, error)) *fanOutServer {
+	s := &fanOutServer{}
+	s.Server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := read(r.Body)
+		s.mu.Lock()
+		s.body = body
+		s.mu.Unlock()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	s.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if buffer > 0 && state == http.StateNew {
+			c.(*net.TCPConn).SetReadBuffer(buffer)
+		}
+	}
+	s.Start()
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *fanOutServer) received() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.body
+}
+
+// slowRead reads r in small chunks with a pause after each
+func slowRead(r io.Reader) ([]byte, error) {
+	var buf bytes.Buffer
+	chunk := make([]byte, 64*1024)
+	for {
+		n, err := r.Read(chunk)
+		buf.Write(chunk[:n])
+		if err == io.EOF {
+			return buf.Bytes(), nil
+		}
+		if err != nil {
+			return buf.Bytes(), err
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// earlyServer answers 202 without reading the body
+func earlyServer(t *testing.T) *httptest.Server {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// fanOutClient sends requests to slow destination with socket write buffer of buffer bytes
+func fanOutClient(slow *fanOutServer, buffer int) *http.Client {
+	dialer := &net.Dialer{}
+	return &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			c, err := dialer.DialContext(ctx, network, addr)
+			if err == nil && addr == slow.Listener.Addr().String() {
+				c.(*net.TCPConn).SetWriteBuffer(buffer)
+			}
+			return c, err
+		},
+	}}
+}
+
+func newFanOutRequests(t *testing.T, urls ...string) []*http.Request {
+	reqs := make([]*http.Request, len(urls))
+	for i, url := range urls {
+		req, err := http.NewRequest(http.MethodPost, url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reqs[i] = req
+	}
+	return reqs
+}
+
+func TestFanOutWaitSlowest(t *testing.T) {
+	want, err := io.ReadAll(newFanOutSource(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fast := newFanOutServer(t, 0, io.ReadAll)
+	slow := newFanOutServer(t, 64*1024, slowRead)
+	early := earlyServer(t)
+
+	mr := newFanOutSource(t)
+	results := mr.FanOut(newFanOutRequests(t, fast.URL, slow.URL, early.URL), FanOutOptions{
+		Client:     fanOutClient(slow, 64*1024),
+		BufferSize: 64 * 1024,
+		Policy:     WaitSlowest,
+	})
+	for _, res := range results {
+		if res.Response != nil {
+			res.Response.Body.Close()
+		}
+	}
+
+	for i, s := range []*fanOutServer{fast, slow} {
+		if err := results[i].Err; err != nil {
+			t.Fatalf("destination %d: %v", i, err)
+		}
+		if code := results[i].Response.StatusCode; code != http.StatusOK {
+			t.Errorf("destination %d answered %d", i, code)
+		}
+		if !bytes.Equal(s.received(), want) {
+			t.Errorf("destination %d received %d bytes, want %d", i, len(s.received()), len(want))
+		}
+	}
+	if results[2].Err != nil || results[2].Response.StatusCode != http.StatusAccepted {
+		t.Errorf("early destination: %v, %v", results[2].Response, results[2].Err)
+	}
+	if mr.Count() != int64(len(want)) {
+		t.Errorf("source read %d bytes, want %d", mr.Count(), len(want))
+	}
+}
+
+func TestFanOutDropSlowest(t *testing.T) {
+	want, err := io.ReadAll(newFanOutSource(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	release := make(chan struct{})
+	fast := newFanOutServer(t, 0, io.ReadAll)
+	stuck := newFanOutServer(t, 8*1024, func(r io.Reader) ([]byte, error) {
+		<-release
+		return io.ReadAll(r)
+	})
+	early := earlyServer(t)
+	// handler of stuck destination must return before servers are closed
+	defer close(release)
+
+	mr := newFanOutSource(t)
+	start := time.Now()
+	results := mr.FanOut(newFanOutRequests(t, fast.URL, stuck.URL, early.URL), FanOutOptions{
+		Client:      fanOutClient(stuck, 8*1024),
+		BufferSize:  32 * 1024,
+		Policy:      DropSlowest,
+		DropTimeout: 100 * time.Millisecond,
+	})
+	for _, res := range results {
+		if res.Response != nil {
+			res.Response.Body.Close()
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("FanOut took %v", elapsed)
+	}
+
+	if results[0].Err != nil || results[0].Response.StatusCode != http.StatusOK {
+		t.Fatalf("fast destination: %v, %v", results[0].Response, results[0].Err)
+	}
+	if !bytes.Equal(fast.received(), want) {
+		t.Errorf("fast destination received %d bytes, want %d", len(fast.received()), len(want))
+	}
+	if !errors.Is(results[1].Err, ErrDropped) || results[1].Response != nil {
+		t.Errorf("stuck destination: %v, %v, want ErrDropped", results[1].Response, results[1].Err)
+	}
+	if results[2].Err != nil || results[2].Response.StatusCode != http.StatusAccepted {
+		t.Errorf("early destination: %v, %v", results[2].Response, results[2].Err)
+	}
+	if mr.Count() != int64(len(want)) {
+		t.Errorf("source read %d bytes, want %d", mr.Count(), len(want))
+	}
+}
@@ -0,0 +1,97 @@
+package multipartreader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/textproto"
+	"os"
+)
+
+// Fingerprint returns deterministic fingerprint of logical content of MultipartReader:
+// media type, ordered part headers and hashes of part contents, the boundary is not included
+// and Content-IDs generated by AddRelatedPart are replaced with position of their part.
+// It can be used as Idempotency-Key header. Parts must be added with AddPart and their
+// bodies must be seekable, they are read in a pre-pass and rewound, so it must be called
+// before the first Read. ErrNotSeekable is returned otherwise
+func (mr *MultipartReader) Fingerprint() (string, error) {
+	if mr.multiReader != nil {
+		return "", fmt.Errorf("%w: reading already started", ErrNotSeekable)
+	}
+	if reason := mr.untracked(); reason != "" {
+		// such content would not change the fingerprint
+		return "", fmt.Errorf("%w: %s", ErrNotSeekable, reason)
+	}
+
+	// generated Content-IDs are random, they are replaced with stable ones
+	ids := map[string]string{}
+	for _, p := range mr.parts {
+		if p.generatedID {
+			ids[p.header.Get("Content-ID")] = fmt.Sprintf("<part %d>", p.index)
+		}
+	}
+
+	h := sha256.New()
+	mediaType, params, err := mime.ParseMediaType(mr.contentType)
+	if err != nil {
+		return "", err
+	}
+	delete(params, "boundary")
+	if id, ok := ids[params["start"]]; ok {
+		params["start"] = id
+	}
+	fmt.Fprintf(h, "%s\r\n", mime.FormatMediaType(mediaType, params))
+
+	for _, p := range mr.parts {
+		sum, err := contentHash(p.body)
+		if err != nil {
+			return "", fmt.Errorf("part %d: %w", p.index, err)
+		}
+		header := p.header
+		if p.generatedID {
+			header = textproto.MIMEHeader{}
+			for k, v := range p.header {
+				header[k] = v
+			}
+			header.Set("Content-ID", ids[p.header.Get("Content-ID")])
+		}
+		fmt.Fprintf(h, "part %d\r\n", p.index)
+		h.Write(encodeHeader(header))
+		h.Write(sum)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// contentHash hashes content of r and rewinds it to the initial position
+func contentHash(r io.Reader) ([]byte, error) {
+	if fs, ok := r.(*fileSource); ok {
+		// hash through separate handle, so the source stays unopened
+		f, err := os.Open(fs.path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		if _, err = f.Seek(fs.pos, io.SeekStart); err != nil {
+			return nil, err
+		}
+		r = f
+	}
+	rs, ok := r.(io.ReadSeeker)
+	if !ok {
+		return nil, ErrNotSeekable
+	}
+	pos, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	if _, err = io.Copy(h, rs); err != nil {
+		return nil, err
+	}
+	if _, err = rs.Seek(pos, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
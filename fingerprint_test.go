@@ -0,0 +1,103 @@
+package multipartreader
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+func TestFingerprintStable(t *testing.T) {
+	builders := []struct {
+		name  string
+		build func(content string) *MultipartReader
+	}{
+		{"form", func(content string) *MultipartReader {
+			mr := New()
+			mr.WriteFields(map[string]string{"a": "1", "b": content})
+			return mr
+		}},
+		{"related", func(content string) *MultipartReader {
+			mr := NewRelated()
+			header := textproto.MIMEHeader{"Content-Type": {"text/html"}}
+			mr.AddRelatedPart("", header, strings.NewReader("<img>"))
+			cid := mr.AddRelatedPart("", textproto.MIMEHeader{"Content-Type": {"image/png"}}, strings.NewReader(content))
+			if err := mr.SetStart(cid); err != nil {
+				t.Fatal(err)
+			}
+			return mr
+		}},
+		{"mtom", func(content string) *MultipartReader {
+			envelope := "<e><data>" + base64.StdEncoding.EncodeToString([]byte(content)) + "</data></e>"
+			mr, err := OptimizeMTOM(SOAP12, strings.NewReader(envelope), MTOMOptions{MinSize: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return mr
+		}},
+	}
+	for _, b := range builders {
+		t.Run(b.name, func(t *testing.T) {
+			first, err := b.build("content").Fingerprint()
+			if err != nil {
+				t.Fatal(err)
+			}
+			second, err := b.build("content").Fingerprint()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if first != second {
+				t.Errorf("the same body has fingerprints %s and %s", first, second)
+			}
+			other, err := b.build("other content").Fingerprint()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if other == first {
+				t.Errorf("different bodies have the same fingerprint %s", first)
+			}
+		})
+	}
+}
+
+func TestFingerprintKeepsReader(t *testing.T) {
+	mr := New()
+	mr.WriteFields(map[string]string{"a": "value"})
+	if _, err := mr.Fingerprint(); err != nil {
+		t.Fatal(err)
+	}
+	if parts := readParts(t, mr); len(parts) != 1 || parts[0].Body != "value" {
+		t.Fatalf("body after Fingerprint is %v", parts)
+	}
+}
+
+func TestFingerprintUntracked(t *testing.T) {
+	proxy := func(value string) *MultipartReader {
+		ct, body := formBody(t, [][2]string{{"a", value}}, nil)
+		d, err := NewDecoder(bytes.NewReader(body), ct, DecoderOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return NewProxy(d)
+	}
+	form := func(value string) *MultipartReader {
+		ct, body := formBody(t, [][2]string{{"a", value}}, nil)
+		return FromMultipartReader(multipart.NewReader(bytes.NewReader(body), boundaryOf(t, ct)))
+	}
+	raw := func(value string) *MultipartReader {
+		mr := New()
+		mr.WriteFields(map[string]string{"a": "1"})
+		mr.AddReader(strings.NewReader(value))
+		return mr
+	}
+	for name, build := range map[string]func(string) *MultipartReader{"NewProxy": proxy, "FromMultipartReader": form, "AddReader": raw} {
+		for _, value := range []string{"one", "two"} {
+			if _, err := build(value).Fingerprint(); !errors.Is(err, ErrNotSeekable) {
+				t.Errorf("%s of %q: got %v, want ErrNotSeekable", name, value, err)
+			}
+		}
+	}
+}
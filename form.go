@@ -0,0 +1,133 @@
+package multipartreader
+
+import (
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"sort"
+	"strings"
+)
+
+// FormOption configures FromForm and FromMultipartReader
+type FormOption func(o *formOptions)
+
+type formOptions struct {
+	preserveHeaders bool
+}
+
+// PreserveHeaders keeps original headers of parts instead of
+// Content-Disposition and Content-Type only.
+// Values of multipart.Form have no headers, they are always rebuilt
+func PreserveHeaders() FormOption {
+	return func(o *formOptions) {
+		o.preserveHeaders = true
+	}
+}
+
+func newFormOptions(opts []FormOption) (o formOptions) {
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return
+}
+
+// FromForm creates new MultipartReader which re-encodes form,
+// values go first, then files, both sorted by name.
+// Files are opened when their content is read and closed at EOF
+func FromForm(form *multipart.Form, opts ...FormOption) (mr *MultipartReader) {
+	o := newFormOptions(opts)
+	mr = New()
+
+	names := make([]string, 0, len(form.Value))
+	for name := range form.Value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range form.Value[name] {
+			mr.AddPart(formHeader(name, "", ""), strings.NewReader(value))
+		}
+	}
+
+	names = names[:0]
+	for name := range form.File {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, fh := range form.File[name] {
+			header := formHeader(name, fh.Filename, fh.Header.Get("Content-Type"))
+			if o.preserveHeaders {
+				header = fh.Header
+			}
+			mr.AddPart(header, &formFile{fh: fh})
+		}
+	}
+	return
+}
+
+// FromMultipartReader creates new MultipartReader which forwards parts of r
+// as they are read, only one part is in flight at a time.
+// With PreserveHeaders parts are forwarded raw, Content-Transfer-Encoding included
+func FromMultipartReader(r *multipart.Reader, opts ...FormOption) (mr *MultipartReader) {
+	o := newFormOptions(opts)
+	mr = New()
+	// same as NewProxy, parts are added while reading
+	mr.streamed = true
+
+	next := func() (*ProxyPart, error) {
+		if o.preserveHeaders {
+			p, err := r.NextRawPart()
+			if err != nil {
+				return nil, err
+			}
+			return &ProxyPart{Header: p.Header, Body: p}, nil
+		}
+		p, err := r.NextPart()
+		if err != nil {
+			return nil, err
+		}
+		return &ProxyPart{Header: formHeader(p.FormName(), p.FileName(), p.Header.Get("Content-Type")), Body: p}, nil
+	}
+	mr.AddReader(&proxyReader{mr: mr, next: next})
+	return
+}
+
+// formHeader builds header of form part, files without content type
+// are sent as application/octet-stream as multipart.Writer does
+func formHeader(name, filename, contentType string) textproto.MIMEHeader {
+	params := map[string]string{"name": name}
+	if filename != "" {
+		params["filename"] = filename
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+	}
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", formatDisposition("form-data", params))
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	return header
+}
+
+// formFile is body of uploaded file, it is opened on first Read and closed at EOF
+type formFile struct {
+	fh  *multipart.FileHeader
+	f   multipart.File
+	pos int64
+}
+
+func (ff *formFile) Read(p []byte) (n int, err error) {
+	if ff.f == nil {
+		if ff.f, err = ff.fh.Open(); err != nil {
+			return
+		}
+	}
+	n, err = ff.f.Read(p)
+	ff.pos += int64(n)
+	if err == io.EOF {
+		ff.f.Close()
+	}
+	return
+}
@@ -0,0 +1,68 @@
+package multipartreader
+
+import (
+	"bytes"
+	"mime/multipart"
+	"strings"
+	"testing"
+)
+
+func TestFromForm(t *testing.T) {
+	ct, body := formBody(t, [][2]string{{"b", "2"}, {"a", "1"}}, [][3]string{{"file", "x.txt", "content"}})
+	r := multipart.NewReader(bytes.NewReader(body), boundaryOf(t, ct))
+	form, err := r.ReadForm(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer form.RemoveAll()
+
+	mr := FromForm(form)
+	size, ok := mr.Len()
+	parts := readParts(t, mr)
+	if !ok || size != mr.Count() {
+		t.Errorf("Len is %d, %v, read %d bytes", size, ok, mr.Count())
+	}
+	want := []struct{ disposition, body string }{
+		{`form-data; name="a"`, "1"},
+		{`form-data; name="b"`, "2"},
+		{`form-data; name="file"; filename="x.txt"`, "content"},
+	}
+	if len(parts) != len(want) {
+		t.Fatalf("got %d parts, want %d", len(parts), len(want))
+	}
+	for i, w := range want {
+		if got := parts[i].Header.Get("Content-Disposition"); got != w.disposition {
+			t.Errorf("part %d disposition is %q, want %q", i, got, w.disposition)
+		}
+		if parts[i].Body != w.body {
+			t.Errorf("part %d body is %q, want %q", i, parts[i].Body, w.body)
+		}
+	}
+}
+
+func TestFromMultipartReader(t *testing.T) {
+	ct, body := formBody(t, [][2]string{{"password", "hunter2"}}, [][3]string{{"file", "x.txt", "content"}})
+	body = bytes.Replace(body, []byte("Content-Type: application/octet-stream"),
+		[]byte("Content-Type: application/octet-stream\r\nX-Custom: yes"), 1)
+
+	for _, preserve := range []bool{false, true} {
+		var opts []FormOption
+		if preserve {
+			opts = append(opts, PreserveHeaders())
+		}
+		mr := FromMultipartReader(multipart.NewReader(bytes.NewReader(body), boundaryOf(t, ct)), opts...)
+		var tap bytes.Buffer
+		mr.SetDebug(&tap, DebugOptions{MaxBody: 100, Redact: []string{"password"}})
+
+		parts := readParts(t, mr)
+		if len(parts) != 2 || parts[0].Body != "hunter2" || parts[1].Body != "content" {
+			t.Fatalf("preserve %v: got %v", preserve, parts)
+		}
+		if got := parts[1].Header.Get("X-Custom") != ""; got != preserve {
+			t.Errorf("preserve %v: X-Custom header kept is %v", preserve, got)
+		}
+		if strings.Contains(tap.String(), "hunter2") {
+			t.Errorf("preserve %v: redacted field is in debug output", preserve)
+		}
+	}
+}
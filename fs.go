@@ -0,0 +1,174 @@
+package multipartreader
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultFieldsDir is pseudo-directory of form fields in PartFS
+const DefaultFieldsDir = "_fields"
+
+// PartFS is read-only fs.FS over parts of indexed multipart body.
+// File parts are files named by sanitized filename, duplicates get numeric suffix,
+// other parts are files in fields directory named by form name
+type PartFS struct {
+	ix        *Index
+	fieldsDir string
+	root      []string       // sorted names in root directory
+	fields    []string       // sorted names in fields directory
+	files     map[string]int // path to part index
+}
+
+// NewFS creates new PartFS over ix, fieldsDir is DefaultFieldsDir if empty
+func NewFS(ix *Index, fieldsDir string) *PartFS {
+	if fieldsDir == "" {
+		fieldsDir = DefaultFieldsDir
+	}
+	// fields directory name is reserved while names are assigned
+	pfs := &PartFS{ix: ix, fieldsDir: fieldsDir, files: map[string]int{fieldsDir: -1}}
+
+	for i, p := range ix.Parts {
+		filename := fsName(dispositionParam(p.Header, "filename"))
+		if filename != "" {
+			pfs.root = append(pfs.root, pfs.add(filename, i))
+			continue
+		}
+		name := fsName(dispositionParam(p.Header, "name"))
+		if name == "" {
+			name = fmt.Sprintf("part-%d", i)
+		}
+		pfs.fields = append(pfs.fields, path.Base(pfs.add(fieldsDir+"/"+name, i)))
+	}
+	delete(pfs.files, fieldsDir)
+	if len(pfs.fields) > 0 {
+		pfs.root = append(pfs.root, fieldsDir)
+	}
+	sort.Strings(pfs.root)
+	sort.Strings(pfs.fields)
+	return pfs
+}
+
+// add registers part i under name, or under name with numeric suffix if it is taken
+func (pfs *PartFS) add(name string, i int) string {
+	ext := path.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	unique := name
+	for n := 1; ; n++ {
+		if _, taken := pfs.files[unique]; !taken {
+			break
+		}
+		unique = fmt.Sprintf("%s-%d%s", stem, n, ext)
+	}
+	pfs.files[unique] = i
+	return path.Base(unique)
+}
+
+// fsName makes valid single path element from client supplied name,
+// empty string is returned if it is unsafe
+func fsName(name string) string {
+	name, err := SanitizeFilename(name, SanitizeOptions{})
+	if err != nil {
+		return ""
+	}
+	return name
+}
+
+// Open implements fs.FS
+func (pfs *PartFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	switch name {
+	case ".":
+		return pfs.dir(".", "", pfs.root), nil
+	case pfs.fieldsDir:
+		if len(pfs.fields) > 0 {
+			return pfs.dir(pfs.fieldsDir, pfs.fieldsDir+"/", pfs.fields), nil
+		}
+	}
+	i, ok := pfs.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &partFile{
+		SectionReader: pfs.ix.Section(i),
+		info:          partFileInfo{name: path.Base(name), size: pfs.ix.Parts[i].Size},
+	}, nil
+}
+
+func (pfs *PartFS) dir(name, prefix string, names []string) *partDir {
+	entries := make([]fs.DirEntry, 0, len(names))
+	for _, n := range names {
+		if i, ok := pfs.files[prefix+n]; ok {
+			entries = append(entries, fs.FileInfoToDirEntry(partFileInfo{name: n, size: pfs.ix.Parts[i].Size}))
+			continue
+		}
+		entries = append(entries, fs.FileInfoToDirEntry(partFileInfo{name: n, dir: true}))
+	}
+	return &partDir{info: partFileInfo{name: path.Base(name), dir: true}, entries: entries}
+}
+
+// partFileInfo implements fs.FileInfo of PartFS entries
+type partFileInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (fi partFileInfo) Name() string       { return fi.name }
+func (fi partFileInfo) Size() int64        { return fi.size }
+func (fi partFileInfo) ModTime() time.Time { return time.Time{} }
+func (fi partFileInfo) IsDir() bool        { return fi.dir }
+func (fi partFileInfo) Sys() any           { return nil }
+
+func (fi partFileInfo) Mode() fs.FileMode {
+	if fi.dir {
+		return fs.ModeDir | 0o555
+	}
+	return 0o444
+}
+
+// partFile is content of part, it is seekable as http.FileServer requires
+type partFile struct {
+	*io.SectionReader
+	info partFileInfo
+}
+
+func (f *partFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *partFile) Close() error               { return nil }
+
+// partDir is directory of PartFS
+type partDir struct {
+	info    partFileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *partDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *partDir) Close() error               { return nil }
+
+func (d *partDir) Read(p []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: fs.ErrInvalid}
+}
+
+// ReadDir implements fs.ReadDirFile
+func (d *partDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+	return rest[:n], nil
+}
@@ -0,0 +1,54 @@
+package multipartreader
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"net/textproto"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestPartFS(t *testing.T) {
+	mr := New()
+	mr.WriteFields(map[string]string{"title": "hello"})
+	mr.AddFormReader("file", "report.txt", strings.NewReader("first"))
+	mr.AddFormReader("file", "report.txt", strings.NewReader("second"))
+	mr.AddFormReader("file", "../../etc/passwd", strings.NewReader("unsafe"))
+	mr.AddPart(textproto.MIMEHeader{"Content-Type": {"text/plain"}}, strings.NewReader("anonymous"))
+	body, err := io.ReadAll(mr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ix, err := NewIndex(bytes.NewReader(body), int64(len(body)), mr.ContentType(), DecoderOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pfs := NewFS(ix, "")
+
+	files := map[string]string{
+		"report.txt":     "first",
+		"report-1.txt":   "second",
+		"_fields/title":  "hello",
+		"_fields/file":   "unsafe",
+		"_fields/part-4": "anonymous",
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	if err = fstest.TestFS(pfs, names...); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range files {
+		got, err := fs.ReadFile(pfs, name)
+		if err != nil || string(got) != want {
+			t.Errorf("%s is %q, %v, want %q", name, got, err, want)
+		}
+	}
+	if _, err = fs.Stat(pfs, "passwd"); err == nil {
+		t.Error("unsafe filename is exposed")
+	}
+}
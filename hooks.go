@@ -0,0 +1,76 @@
+package multipartreader
+
+import (
+	"log/slog"
+	"time"
+)
+
+// hooks are called during the lifecycle of MultipartReader,
+// they feed logger and observers
+
+func (mr *MultipartReader) partAdded(p *part) {
+	mr.log(mr.logLevel, "multipart part added", partAttrs(p)...)
+}
+
+func (mr *MultipartReader) bodyStarted() {
+	mr.stats.bodyStart(mr.lenOrUnknown)
+	for _, o := range mr.observers {
+		o.BodyStart()
+	}
+}
+
+func (mr *MultipartReader) partStarted(p *part) {
+	mr.stats.partStart(p)
+	mr.log(mr.logLevel, "multipart part started", partAttrs(p)...)
+	for _, o := range mr.observers {
+		o.PartStart(p.info())
+	}
+}
+
+func (mr *MultipartReader) bytesRead(n int) {
+	mr.stats.bytesRead(n)
+	for _, o := range mr.observers {
+		o.BytesRead(n)
+	}
+}
+
+func (mr *MultipartReader) partFinished(p *part, err error) {
+	mr.stats.partFinish(p)
+	for _, o := range mr.observers {
+		o.PartFinish(p.info(), err)
+	}
+	attrs := append(partAttrs(p),
+		slog.Int64("size", p.size),
+		slog.Duration("duration", time.Since(p.started)),
+	)
+	if err != nil {
+		mr.log(slog.LevelError, "multipart part failed", append(attrs, slog.Any("error", err))...)
+		return
+	}
+	mr.log(mr.logLevel, "multipart part finished", attrs...)
+}
+
+func (mr *MultipartReader) bodyFinished(err error) {
+	mr.stats.bodyFinish()
+	for _, o := range mr.observers {
+		o.BodyFinish(mr.Count(), err)
+	}
+	attrs := []slog.Attr{
+		slog.Int("parts", len(mr.parts)),
+		slog.Int64("bytes", mr.Count()),
+		slog.Duration("duration", time.Since(mr.started)),
+	}
+	if err != nil {
+		mr.log(slog.LevelError, "multipart body failed", append(attrs, slog.Any("error", err))...)
+		return
+	}
+	mr.log(mr.logLevel, "multipart body finished", attrs...)
+}
+
+// retried is called by upload helpers before request is sent again
+func (mr *MultipartReader) retried(attempt int, err error) {
+	mr.log(mr.logLevel, "multipart retry", slog.Int("attempt", attempt), slog.Any("error", err))
+	for _, o := range mr.observers {
+		o.Retry(attempt, err)
+	}
+}
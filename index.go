@@ -0,0 +1,79 @@
+package multipartreader
+
+import (
+	"io"
+	"net/textproto"
+)
+
+// IndexedPart is location of a part in multipart body
+type IndexedPart struct {
+	Index  int
+	Header textproto.MIMEHeader
+	// HeaderOffset is offset of the part header, right after delimiter line
+	HeaderOffset int64
+	// ContentOffset is offset of the part content
+	ContentOffset int64
+	Size          int64
+}
+
+// Index is random-access index over multipart body stored in a file,
+// it is built with a single scan of the body
+type Index struct {
+	Boundary  string
+	Parts     []IndexedPart
+	Anomalies []Anomaly
+
+	ra io.ReaderAt
+}
+
+// NewIndex scans multipart body of size bytes in ra and records offsets of its parts
+func NewIndex(ra io.ReaderAt, size int64, contentType string, opts DecoderOptions) (ix *Index, err error) {
+	d, err := NewDecoder(io.NewSectionReader(ra, 0, size), contentType, opts)
+	if err != nil {
+		return
+	}
+	ix = &Index{ra: ra}
+	for {
+		var p *Part
+		if p, err = d.NextPart(); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		if err = p.Close(); err != nil {
+			return nil, err
+		}
+		ix.Parts = append(ix.Parts, IndexedPart{
+			Index:         p.Index,
+			Header:        p.Header,
+			HeaderOffset:  p.headerOffset,
+			ContentOffset: p.contentOffset,
+			Size:          d.offset() - p.contentOffset,
+		})
+	}
+	ix.Boundary = d.Boundary()
+	ix.Anomalies = d.Anomalies()
+	return ix, nil
+}
+
+// Section returns reader of content of part i, readers are safe for concurrent use
+func (ix *Index) Section(i int) *io.SectionReader {
+	p := ix.Parts[i]
+	return io.NewSectionReader(ix.ra, p.ContentOffset, p.Size)
+}
+
+// Lookup returns index of the first part with form name, -1 if none
+func (ix *Index) Lookup(name string) int {
+	for i, p := range ix.Parts {
+		if dispositionParam(p.Header, "name") == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Attach sets body of Index which was stored separately, e.g. as JSON
+func (ix *Index) Attach(ra io.ReaderAt) {
+	ix.ra = ra
+}
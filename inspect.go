@@ -0,0 +1,79 @@
+package multipartreader
+
+import (
+	"bytes"
+	"fmt"
+	"net/textproto"
+)
+
+// Inspector sees every part while it flows through Read,
+// returned error vetoes the upload
+type Inspector interface {
+	// InspectHeader is called before content of part is read
+	InspectHeader(header textproto.MIMEHeader) error
+	// InspectChunk is called for every chunk of part content
+	InspectChunk(chunk []byte) error
+	// InspectEnd is called after the last chunk, before it is sent
+	InspectEnd() error
+}
+
+// InspectionError is returned from Read when Inspector vetoes a part
+type InspectionError struct {
+	Part   int
+	Header textproto.MIMEHeader
+	Err    error
+}
+
+func (e *InspectionError) Error() string {
+	return fmt.Sprintf("multipartreader: part %d rejected: %v", e.Part, e.Err)
+}
+
+func (e *InspectionError) Unwrap() error {
+	return e.Err
+}
+
+// SetInspector sets Inspector for all parts added with AddPart
+func (mr *MultipartReader) SetInspector(in Inspector) {
+	mr.inspector = in
+}
+
+// SignatureInspector is Inspector which rejects parts containing any of byte signatures
+type SignatureInspector struct {
+	// Signatures maps signature name to its bytes
+	Signatures map[string][]byte
+
+	tail []byte
+}
+
+// InspectHeader starts new part
+func (si *SignatureInspector) InspectHeader(header textproto.MIMEHeader) error {
+	si.tail = si.tail[:0]
+	return nil
+}
+
+// InspectChunk looks for signatures, including ones split between chunks
+func (si *SignatureInspector) InspectChunk(chunk []byte) error {
+	data := append(si.tail, chunk...)
+	longest := 0
+	for name, sig := range si.Signatures {
+		if len(sig) > 0 && bytes.Contains(data, sig) {
+			return fmt.Errorf("signature %q found", name)
+		}
+		if len(sig) > longest {
+			longest = len(sig)
+		}
+	}
+	keep := longest - 1
+	if keep < 0 {
+		keep = 0
+	}
+	if keep > len(data) {
+		keep = len(data)
+	}
+	si.tail = append(si.tail[:0], data[len(data)-keep:]...)
+	return nil
+}
+
+func (si *SignatureInspector) InspectEnd() error {
+	return nil
+}
@@ -0,0 +1,72 @@
+package multipartreader
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/textproto"
+	"testing"
+)
+
+// chunkReader returns one chunk per Read
+type chunkReader struct {
+	chunks []string
+}
+
+func (cr *chunkReader) Read(p []byte) (int, error) {
+	if len(cr.chunks) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, cr.chunks[0])
+	cr.chunks[0] = cr.chunks[0][n:]
+	if cr.chunks[0] == "" {
+		cr.chunks = cr.chunks[1:]
+	}
+	return n, nil
+}
+
+func TestInspectorVetoIsSticky(t *testing.T) {
+	mr := New()
+	mr.SetInspector(&SignatureInspector{Signatures: map[string][]byte{"evil": []byte("EVIL")}})
+	header := textproto.MIMEHeader{"Content-Disposition": {`form-data; name="file"`}}
+	mr.AddPart(header, &chunkReader{chunks: []string{"ok ", "EVIL", "rest"}})
+	mr.WriteFields(map[string]string{"after": "value"})
+
+	var out bytes.Buffer
+	buf := make([]byte, 16)
+	var first error
+	for i := 0; i < 20; i++ {
+		n, err := mr.Read(buf)
+		out.Write(buf[:n])
+		if err == nil {
+			continue
+		}
+		if first == nil {
+			first = err
+		}
+		if err != first {
+			t.Fatalf("read %d after veto returned %v, want %v", i, err, first)
+		}
+	}
+
+	var ie *InspectionError
+	if !errors.As(first, &ie) || ie.Part != 0 {
+		t.Fatalf("got %v, want InspectionError of part 0", first)
+	}
+	for _, leaked := range []string{"EVIL", "rest", "value", "--" + mr.Boundary() + "--"} {
+		if bytes.Contains(out.Bytes(), []byte(leaked)) {
+			t.Errorf("output contains %q after veto:\n%s", leaked, out.Bytes())
+		}
+	}
+}
+
+func TestSignatureInspectorSplitChunks(t *testing.T) {
+	mr := New()
+	mr.SetInspector(&SignatureInspector{Signatures: map[string][]byte{"evil": []byte("EVIL")}})
+	mr.AddPart(textproto.MIMEHeader{}, &chunkReader{chunks: []string{"xxEV", "ILxx"}})
+
+	var ie *InspectionError
+	if _, err := io.ReadAll(mr); !errors.As(err, &ie) {
+		t.Fatalf("got %v, want InspectionError", err)
+	}
+}
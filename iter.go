@@ -0,0 +1,41 @@
+package multipartreader
+
+import (
+	"io"
+	"iter"
+)
+
+// Parts returns iterator over remaining parts of the body:
+//
+//	for part, err := range dec.Parts() {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// Each part is drained when the loop advances or stops, error ends the iteration
+func (d *Decoder) Parts() iter.Seq2[*Part, error] {
+	return func(yield func(*Part, error) bool) {
+		for {
+			p, err := d.NextPart()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			more := yield(p, nil)
+			if err = p.Close(); err != nil {
+				if more {
+					yield(nil, err)
+				}
+				return
+			}
+			if !more {
+				return
+			}
+		}
+	}
+}
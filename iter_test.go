@@ -0,0 +1,113 @@
+package multipartreader
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParts(t *testing.T) {
+	ct, body := formBody(t,
+		[][2]string{{"a", "1"}, {"b", strings.Repeat("2", 100000)}},
+		[][3]string{{"file", "f.txt", "content"}},
+	)
+	d, err := NewDecoder(bytes.NewReader(body), ct, DecoderOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names, contents []string
+	for p, err := range d.Parts() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, p.FormName())
+		contents = append(contents, string(content))
+	}
+	if want := []string{"a", "b", "file"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("parts are %q, want %q", names, want)
+	}
+	if want := []string{"1", strings.Repeat("2", 100000), "content"}; !reflect.DeepEqual(contents, want) {
+		t.Errorf("contents differ")
+	}
+	if _, err = d.NextPart(); err != io.EOF {
+		t.Errorf("got %v after iteration, want io.EOF", err)
+	}
+}
+
+func TestPartsBreak(t *testing.T) {
+	ct, body := formBody(t, [][2]string{{"a", strings.Repeat("1", 100000)}, {"b", "2"}}, nil)
+	d, err := NewDecoder(bytes.NewReader(body), ct, DecoderOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for p, err := range d.Parts() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		// part is left half read
+		if _, err = io.ReadFull(p, make([]byte, 10)); err != nil {
+			t.Fatal(err)
+		}
+		break
+	}
+
+	p, err := d.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := io.ReadAll(p)
+	if err != nil || p.FormName() != "b" || string(content) != "2" {
+		t.Errorf("part after break is %q with %q, %v", p.FormName(), content, err)
+	}
+	if _, err = d.NextPart(); err != io.EOF {
+		t.Errorf("got %v after the last part, want io.EOF", err)
+	}
+}
+
+func TestPartsError(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		parts int
+	}{
+		{"malformed header", "--b\r\nbad header\r\n\r\nx\r\n--b--\r\n", 0},
+		{"malformed second part", "--b\r\nA: 1\r\n\r\nx\r\n--b\r\nbad header\r\n\r\ny\r\n--b--\r\n", 1},
+		// part is yielded, error comes from draining it when the loop advances
+		{"truncated part", "--b\r\nA: 1\r\n\r\nx", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := NewDecoder(strings.NewReader(tt.body), "multipart/form-data; boundary=b", DecoderOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			parts, errs := 0, 0
+			for p, err := range d.Parts() {
+				if err != nil {
+					if !errors.Is(err, ErrMalformed) {
+						t.Errorf("got %v, want ErrMalformed", err)
+					}
+					errs++
+					continue
+				}
+				if errs > 0 {
+					t.Errorf("part after error")
+				}
+				parts++
+				io.Copy(io.Discard, p)
+			}
+			if parts != tt.parts || errs != 1 {
+				t.Errorf("got %d parts and %d errors, want %d parts and 1 error", parts, errs, tt.parts)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package multipartreader
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// maxHeaderBytes is header limit of a part when Limits don't set one
+const maxHeaderBytes = 1 << 20
+
+// Limits are hard limits of Decoder, zero means no limit
+type Limits struct {
+	// MaxHeaderBytes limits header size of a part, 1MB if zero
+	MaxHeaderBytes int
+	// MaxHeaders limits number of header lines of a part
+	MaxHeaders int
+	MaxParts   int
+	// MaxFieldSize limits content of parts without filename
+	MaxFieldSize int64
+	// MaxFileSize limits content of parts with filename
+	MaxFileSize int64
+	// MaxTotalSize limits the whole body
+	MaxTotalSize int64
+	// MinThroughput is minimal rate in bytes per second over ThroughputWindow,
+	// it catches slowly trickling clients, while stalled ones need read deadline
+	// of the connection, e.g. http.Server.ReadTimeout
+	MinThroughput    int64
+	ThroughputWindow time.Duration
+}
+
+// LimitError is returned by Decoder when body exceeds Limits
+type LimitError struct {
+	// Limit is name of Limits field which was exceeded
+	Limit string
+	Value int64
+	// Part is index of part, -1 for limits of the whole body
+	Part int
+}
+
+func (e *LimitError) Error() string {
+	if e.Part < 0 {
+		return fmt.Sprintf("multipartreader: body exceeds %s (%d)", e.Limit, e.Value)
+	}
+	return fmt.Sprintf("multipartreader: part %d exceeds %s (%d)", e.Part, e.Limit, e.Value)
+}
+
+// partMax returns content limit of p and its name
+func (l Limits) partMax(p *Part) (int64, string) {
+	if p.FileName() != "" {
+		return l.MaxFileSize, "MaxFileSize"
+	}
+	return l.MaxFieldSize, "MaxFieldSize"
+}
+
+// countingReader counts bytes read from r and enforces limits of the whole body
+type countingReader struct {
+	r      io.Reader
+	n      int64
+	limits Limits
+
+	windowStart time.Time
+	windowBytes int64
+}
+
+func (cr *countingReader) Read(p []byte) (n int, err error) {
+	l := cr.limits
+	if l.MaxTotalSize > 0 {
+		left := l.MaxTotalSize - cr.n
+		if left <= 0 {
+			// allow to see EOF right at the limit
+			var b [1]byte
+			if n, err = cr.r.Read(b[:]); n > 0 {
+				return 0, &LimitError{Limit: "MaxTotalSize", Value: l.MaxTotalSize, Part: -1}
+			}
+			return 0, err
+		}
+		if int64(len(p)) > left {
+			p = p[:left]
+		}
+	}
+
+	n, err = cr.r.Read(p)
+	cr.n += int64(n)
+
+	if l.MinThroughput > 0 && l.ThroughputWindow > 0 {
+		now := time.Now()
+		if cr.windowStart.IsZero() {
+			cr.windowStart = now
+		}
+		cr.windowBytes += int64(n)
+		if elapsed := now.Sub(cr.windowStart); elapsed >= l.ThroughputWindow {
+			if float64(cr.windowBytes)/elapsed.Seconds() < float64(l.MinThroughput) && err == nil {
+				err = &LimitError{Limit: "MinThroughput", Value: l.MinThroughput, Part: -1}
+			}
+			cr.windowStart = now
+			cr.windowBytes = 0
+		}
+	}
+	return
+}
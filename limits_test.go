@@ -0,0 +1,175 @@
+package multipartreader
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// decodeAll reads all parts and their content, returns the first error
+func decodeAll(r io.Reader, contentType string, opts DecoderOptions) (parts int, err error) {
+	d, err := NewDecoder(r, contentType, opts)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		p, err := d.NextPart()
+		if err == io.EOF {
+			return parts, nil
+		}
+		if err != nil {
+			return parts, err
+		}
+		parts++
+		if _, err = io.Copy(io.Discard, p); err != nil {
+			return parts, err
+		}
+	}
+}
+
+// slowReader returns one byte per Read after delay
+type slowReader struct {
+	r     io.Reader
+	delay time.Duration
+}
+
+func (sr *slowReader) Read(p []byte) (int, error) {
+	time.Sleep(sr.delay)
+	return sr.r.Read(p[:1])
+}
+
+func TestLimits(t *testing.T) {
+	ct, body := formBody(t,
+		[][2]string{{"a", "0123456789"}, {"b", "x"}},
+		[][3]string{{"file", "f.txt", strings.Repeat("z", 20)}},
+	)
+	// header of the file part is the longest one, blank line included
+	header := len("Content-Disposition: form-data; name=\"file\"; filename=\"f.txt\"\r\n" +
+		"Content-Type: application/octet-stream\r\n\r\n")
+
+	tests := []struct {
+		name   string
+		limits Limits
+		limit  string // name of exceeded limit, empty if body fits
+		part   int
+	}{
+		{"header bytes at limit", Limits{MaxHeaderBytes: header}, "", 0},
+		{"header bytes over limit", Limits{MaxHeaderBytes: header - 1}, "MaxHeaderBytes", 2},
+		{"headers at limit", Limits{MaxHeaders: 2}, "", 0},
+		{"headers over limit", Limits{MaxHeaders: 1}, "MaxHeaders", 2},
+		{"parts at limit", Limits{MaxParts: 3}, "", 0},
+		{"parts over limit", Limits{MaxParts: 2}, "MaxParts", 2},
+		{"field size at limit", Limits{MaxFieldSize: 10}, "", 0},
+		{"field size over limit", Limits{MaxFieldSize: 9}, "MaxFieldSize", 0},
+		{"file size at limit", Limits{MaxFileSize: 20}, "", 0},
+		{"file size over limit", Limits{MaxFileSize: 19}, "MaxFileSize", 2},
+		{"field limit ignores files", Limits{MaxFieldSize: 10, MaxFileSize: 20}, "", 0},
+		{"total size at limit", Limits{MaxTotalSize: int64(len(body))}, "", 0},
+		{"total size over limit", Limits{MaxTotalSize: int64(len(body)) - 1}, "MaxTotalSize", -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts, err := decodeAll(bytes.NewReader(body), ct, DecoderOptions{Limits: tt.limits})
+			if tt.limit == "" {
+				if err != nil || parts != 3 {
+					t.Fatalf("got %d parts, %v, want 3 parts", parts, err)
+				}
+				return
+			}
+			var le *LimitError
+			if !errors.As(err, &le) {
+				t.Fatalf("got %v, want LimitError", err)
+			}
+			if le.Limit != tt.limit || le.Part != tt.part {
+				t.Fatalf("got %s of part %d, want %s of part %d", le.Limit, le.Part, tt.limit, tt.part)
+			}
+		})
+	}
+}
+
+func TestMinThroughput(t *testing.T) {
+	ct, body := formBody(t, [][2]string{{"a", strings.Repeat("x", 100)}}, nil)
+	limits := Limits{MinThroughput: 1000, ThroughputWindow: 20 * time.Millisecond}
+
+	if _, err := decodeAll(bytes.NewReader(body), ct, DecoderOptions{Limits: limits}); err != nil {
+		t.Fatalf("fast body: %v", err)
+	}
+
+	_, err := decodeAll(&slowReader{r: bytes.NewReader(body), delay: 5 * time.Millisecond}, ct, DecoderOptions{Limits: limits})
+	var le *LimitError
+	if !errors.As(err, &le) || le.Limit != "MinThroughput" || le.Part != -1 {
+		t.Fatalf("slow body: got %v, want MinThroughput LimitError", err)
+	}
+}
+
+// countReader counts bytes read from r
+type countReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countReader) Read(p []byte) (n int, err error) {
+	n, err = cr.r.Read(p)
+	cr.n += int64(n)
+	return
+}
+
+func FuzzDecoder(f *testing.F) {
+	_, body := formBody(f,
+		[][2]string{{"a", "value"}, {"b", ""}},
+		[][3]string{{"file", "f.txt", "content\r\n--not a delimiter"}},
+	)
+	f.Add(string(body), false)
+	f.Add(strings.ReplaceAll(string(body), "\r\n", "\n"), true)
+	f.Add("preamble\r\n--b \r\nA: 1\r\n\r\nx\r\n--b", true)
+	f.Add("--b\r\nA: 1\r\n folded\r\n\r\n\r\n--b--", false)
+
+	limits := Limits{
+		MaxHeaderBytes: 256,
+		MaxHeaders:     4,
+		MaxParts:       4,
+		MaxFieldSize:   64,
+		MaxFileSize:    128,
+		MaxTotalSize:   1024,
+	}
+	f.Fuzz(func(t *testing.T, body string, lenient bool) {
+		src := &countReader{r: strings.NewReader(body)}
+		d, err := NewDecoder(src, "", DecoderOptions{SniffBoundary: true, Lenient: lenient, Limits: limits})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for parts := 0; ; parts++ {
+			p, err := d.NextPart()
+			if err != nil {
+				break
+			}
+			if parts >= limits.MaxParts {
+				t.Fatalf("part %d returned over MaxParts", parts)
+			}
+			headerBytes := 0
+			for k, vs := range p.Header {
+				for _, v := range vs {
+					headerBytes += len(k) + len(v)
+				}
+			}
+			if headerBytes > limits.MaxHeaderBytes {
+				t.Fatalf("part %d has %d header bytes", parts, headerBytes)
+			}
+			content, err := io.ReadAll(p)
+			max, _ := limits.partMax(p)
+			if int64(len(content)) > max {
+				t.Fatalf("part %d has %d bytes of content, limit is %d", parts, len(content), max)
+			}
+			if err != nil {
+				break
+			}
+		}
+		// one byte is read over the limit to tell it from the end of body
+		if src.n > limits.MaxTotalSize+1 {
+			t.Fatalf("%d bytes read, MaxTotalSize is %d", src.n, limits.MaxTotalSize)
+		}
+	})
+}
@@ -0,0 +1,49 @@
+package multipartreader
+
+import (
+	"context"
+	"log/slog"
+	"mime"
+	"net/http"
+)
+
+// SetLogger sets logger for lifecycle events of MultipartReader.
+// Events are logged at level, errors at slog.LevelError, content is never logged
+func (mr *MultipartReader) SetLogger(l *slog.Logger, level slog.Level) {
+	mr.logger = l
+	mr.logLevel = level
+}
+
+// partAttrs describes part without its content
+func partAttrs(p *part) []slog.Attr {
+	attrs := []slog.Attr{slog.Int("part", p.index)}
+	if _, params, err := mime.ParseMediaType(p.header.Get("Content-Disposition")); err == nil {
+		if params["name"] != "" {
+			attrs = append(attrs, slog.String("name", params["name"]))
+		}
+		if params["filename"] != "" {
+			attrs = append(attrs, slog.String("filename", params["filename"]))
+		}
+	}
+	if ct := p.header.Get("Content-Type"); ct != "" {
+		attrs = append(attrs, slog.String("content_type", ct))
+	}
+	return attrs
+}
+
+func (mr *MultipartReader) log(level slog.Level, msg string, attrs ...slog.Attr) {
+	if mr.logger == nil {
+		return
+	}
+	mr.logger.LogAttrs(context.Background(), level, msg, attrs...)
+}
+
+// logResult logs result of request sent by an upload helper
+func (mr *MultipartReader) logResult(msg string, req *http.Request, resp *http.Response, err error) {
+	attrs := []slog.Attr{slog.String("url", req.URL.Redacted())}
+	if err != nil {
+		mr.log(slog.LevelError, msg, append(attrs, slog.Any("error", err))...)
+		return
+	}
+	mr.log(mr.logLevel, msg, append(attrs, slog.Int("status", resp.StatusCode))...)
+}
@@ -0,0 +1,130 @@
+package multipartreader
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+)
+
+// syncBuffer is bytes.Buffer safe for writes from transport goroutines
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (sb *syncBuffer) Write(p []byte) (int, error) {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return sb.b.Write(p)
+}
+
+func (sb *syncBuffer) Bytes() []byte {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return append([]byte(nil), sb.b.Bytes()...)
+}
+
+// logRecords decodes records written by slog.JSONHandler
+func logRecords(t *testing.T, out []byte) (records []map[string]any) {
+	t.Helper()
+	for _, line := range bytes.Split(bytes.TrimSpace(out), []byte("\n")) {
+		var r map[string]any
+		if err := json.Unmarshal(line, &r); err != nil {
+			t.Fatalf("record %s: %v", line, err)
+		}
+		records = append(records, r)
+	}
+	return
+}
+
+// findRecord returns the first record with message msg
+func findRecord(records []map[string]any, msg string) map[string]any {
+	for _, r := range records {
+		if r["msg"] == msg {
+			return r
+		}
+	}
+	return nil
+}
+
+func TestSetLoggerUpload(t *testing.T) {
+	const secret = "secret-field-value"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+	}))
+	defer srv.Close()
+
+	var out syncBuffer
+	mr := New()
+	mr.SetLogger(slog.New(slog.NewJSONHandler(&out, nil)), slog.LevelInfo)
+	mr.WriteFields(map[string]string{"password": secret})
+	mr.AddFormReader("file", "f.txt", strings.NewReader(strings.Repeat(secret, 100)))
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/upload", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := mr.Upload(nil, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	logged := out.Bytes()
+	if bytes.Contains(logged, []byte(secret)) {
+		t.Errorf("content is logged:\n%s", logged)
+	}
+	records := logRecords(t, logged)
+	for _, msg := range []string{"multipart part added", "multipart part started", "multipart part finished", "multipart upload"} {
+		if findRecord(records, msg) == nil {
+			t.Errorf("no %q record:\n%s", msg, logged)
+		}
+	}
+	finished := findRecord(records, "multipart body finished")
+	if finished == nil {
+		t.Fatalf("no body finished record:\n%s", logged)
+	}
+	if finished["parts"] != float64(2) || finished["bytes"] != float64(mr.Count()) || finished["duration"] == nil {
+		t.Errorf("body finished with %v, want 2 parts of %d bytes", finished, mr.Count())
+	}
+	if upload := findRecord(records, "multipart upload"); upload["status"] != float64(http.StatusOK) {
+		t.Errorf("upload logged with %v", upload)
+	}
+}
+
+func TestSetLoggerFailure(t *testing.T) {
+	var out syncBuffer
+	mr := New()
+	mr.SetLogger(slog.New(slog.NewJSONHandler(&out, nil)), slog.LevelDebug)
+	mr.AddPart(textproto.MIMEHeader{}, io.MultiReader(strings.NewReader("partial"), iotest.ErrReader(errors.New("disk failed"))))
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	mr.SetupRequest(req)
+	if _, err := io.ReadAll(req.Body); err == nil {
+		t.Fatal("body read without error")
+	}
+
+	logged := out.Bytes()
+	if bytes.Contains(logged, []byte("partial")) {
+		t.Errorf("content is logged:\n%s", logged)
+	}
+	records := logRecords(t, logged)
+	for _, msg := range []string{"multipart part failed", "multipart body failed"} {
+		r := findRecord(records, msg)
+		if r == nil {
+			t.Fatalf("no %q record:\n%s", msg, logged)
+		}
+		if r["level"] != "ERROR" || r["error"] != "disk failed" {
+			t.Errorf("%q logged with %v", msg, r)
+		}
+	}
+}
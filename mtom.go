@@ -0,0 +1,212 @@
+package multipartreader
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/textproto"
+	"strings"
+)
+
+// SOAP envelope content types used by NewMTOM
+const (
+	SOAP11 = "text/xml"
+	SOAP12 = "application/soap+xml"
+)
+
+// ErrNoContentID is returned from Read when MTOM attachment has empty Content-ID,
+// envelope can not reference it
+var ErrNoContentID = errors.New("multipartreader: MTOM attachment has no Content-ID")
+
+// xmimeNS is namespace of xmime:contentType attribute (W3C Describing Media Content of Binary Data in XML)
+const xmimeNS = "http://www.w3.org/2005/05/xmlmime"
+
+// MTOMAttachment is binary content referenced from XOP envelope
+type MTOMAttachment struct {
+	// ContentID is referenced from envelope with XOPInclude, see NewContentID
+	ContentID   string
+	ContentType string
+	Body        io.Reader
+}
+
+// XOPInclude returns xop:Include element referencing part with Content-ID cid
+func XOPInclude(cid string) string {
+	return fmt.Sprintf(`<xop:Include xmlns:xop="http://www.w3.org/2004/08/xop/include" href="%s"/>`, CIDURL(cid))
+}
+
+// NewMTOM creates new MultipartReader with MTOM/XOP encoded SOAP message.
+// soapType is SOAP11 or SOAP12, envelope must be already XOP-optimized,
+// i.e. binary fields are replaced with XOPInclude of attachments Content-IDs,
+// see OptimizeMTOM otherwise. Read fails with ErrNoContentID if any attachment has none.
+// Attachments are streamed as separate parts after the envelope
+func NewMTOM(soapType string, envelope io.Reader, attachments ...*MTOMAttachment) (mr *MultipartReader) {
+	mr = NewRelated()
+	mr.SetRelatedParam("start-info", soapType)
+
+	root := textproto.MIMEHeader{}
+	root.Set("Content-Type", mime.FormatMediaType("application/xop+xml", map[string]string{
+		"charset": "UTF-8",
+		"type":    soapType,
+	}))
+	root.Set("Content-Transfer-Encoding", "8bit")
+	start := mr.AddRelatedPart("", root, envelope)
+
+	for _, a := range attachments {
+		if a.ContentID == "" && mr.err == nil {
+			mr.err = ErrNoContentID
+		}
+		header := textproto.MIMEHeader{}
+		contentType := a.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		header.Set("Content-Type", contentType)
+		header.Set("Content-Transfer-Encoding", "binary")
+		mr.AddRelatedPart(a.ContentID, header, a.Body)
+	}
+
+	// start is known to exist
+	_ = mr.SetStart(start)
+	return
+}
+
+// MTOMOptions selects elements of envelope which OptimizeMTOM moves to attachments
+type MTOMOptions struct {
+	// Elements lists elements with base64 content,
+	// element with empty Space matches any namespace
+	Elements []xml.Name
+	// MinSize also selects any element whose content is valid base64
+	// of at least MinSize bytes, zero disables it
+	MinSize int
+}
+
+// OptimizeMTOM creates new MultipartReader with MTOM/XOP encoded SOAP message
+// from envelope with inline base64 content. Content of selected elements is decoded
+// into attachments and replaced with XOPInclude, content type of attachment is taken
+// from xmime:contentType attribute. The rest of envelope is kept byte for byte.
+// Envelope is read into memory, Content-IDs are derived from attachment content
+// so the same envelope gives the same message
+func OptimizeMTOM(soapType string, envelope io.Reader, opts MTOMOptions) (mr *MultipartReader, err error) {
+	data, err := io.ReadAll(envelope)
+	if err != nil {
+		return
+	}
+
+	var (
+		stack       []*mtomElement
+		out         bytes.Buffer
+		copied      int64
+		attachments []*MTOMAttachment
+	)
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	prev := dec.InputOffset()
+	for {
+		tok, terr := dec.Token()
+		if terr == io.EOF {
+			break
+		}
+		if terr != nil {
+			return nil, terr
+		}
+		offset := dec.InputOffset()
+
+		var top *mtomElement
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			if top != nil {
+				top.leaf = false
+			}
+			e := &mtomElement{name: tok.Name, start: offset, leaf: true}
+			for _, attr := range tok.Attr {
+				if attr.Name.Space == xmimeNS && attr.Name.Local == "contentType" {
+					e.contentType = attr.Value
+				}
+			}
+			stack = append(stack, e)
+		case xml.CharData:
+			if top != nil {
+				top.text.Write(tok)
+			}
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+			// prev is offset of the end tag
+			content, ok := top.binary(opts)
+			if !ok {
+				break
+			}
+			a := &MTOMAttachment{
+				ContentID:   mtomContentID(content, len(attachments)),
+				ContentType: top.contentType,
+				Body:        bytes.NewReader(content),
+			}
+			attachments = append(attachments, a)
+			out.Write(data[copied:top.start])
+			out.WriteString(XOPInclude(a.ContentID))
+			copied = prev
+		default:
+			// comments and instructions are not part of base64 content
+			if top != nil {
+				top.leaf = false
+			}
+		}
+		prev = offset
+	}
+	out.Write(data[copied:])
+
+	return NewMTOM(soapType, bytes.NewReader(out.Bytes()), attachments...), nil
+}
+
+// mtomElement is open element of envelope in OptimizeMTOM
+type mtomElement struct {
+	name        xml.Name
+	contentType string
+	start       int64 // offset of content
+	text        bytes.Buffer
+	leaf        bool
+}
+
+// binary returns decoded content of e if it is selected by opts
+func (e *mtomElement) binary(opts MTOMOptions) (content []byte, ok bool) {
+	if !e.leaf {
+		return nil, false
+	}
+	selected := false
+	for _, name := range opts.Elements {
+		if name.Local == e.name.Local && (name.Space == "" || name.Space == e.name.Space) {
+			selected = true
+			break
+		}
+	}
+	if !selected && (opts.MinSize <= 0 || e.text.Len() < opts.MinSize) {
+		return nil, false
+	}
+	if content, ok = decodeBase64(e.text.Bytes()); !ok {
+		return nil, false
+	}
+	return content, selected || len(content) >= opts.MinSize
+}
+
+// mtomContentID derives Content-ID of i-th attachment from its content
+func mtomContentID(content []byte, i int) string {
+	sum := sha256.Sum256(content)
+	return fmt.Sprintf("%d.%s@multipartreader", i, hex.EncodeToString(sum[:16]))
+}
+
+// decodeBase64 decodes base64 content of element, whitespace is ignored
+func decodeBase64(text []byte) ([]byte, bool) {
+	s := strings.Join(strings.Fields(string(text)), "")
+	if s == "" {
+		return nil, false
+	}
+	content, err := base64.StdEncoding.DecodeString(s)
+	return content, err == nil
+}
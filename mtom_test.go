@@ -0,0 +1,89 @@
+package multipartreader
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+const testEnvelope = `<?xml version="1.0"?>
+<soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope" xmlns:m="urn:test" xmlns:xmime="http://www.w3.org/2005/05/xmlmime">
+<soap:Body><m:Upload>
+<m:Name>abcd</m:Name>
+<m:Photo xmime:contentType="image/png">{photo}</m:Photo>
+<m:Blob>
+{blob}
+</m:Blob>
+</m:Upload></soap:Body>
+</soap:Envelope>`
+
+func TestOptimizeMTOM(t *testing.T) {
+	photo := []byte("\x89PNG\r\n\x1a\n binary photo")
+	blob := bytes.Repeat([]byte{0, 1, 2, 3}, 100)
+	envelope := strings.NewReplacer(
+		"{photo}", base64.StdEncoding.EncodeToString(photo),
+		"{blob}", base64.StdEncoding.EncodeToString(blob),
+	).Replace(testEnvelope)
+
+	tests := []struct {
+		name  string
+		opts  MTOMOptions
+		parts []string
+	}{
+		{"elements", MTOMOptions{Elements: []xml.Name{{Space: "urn:test", Local: "Photo"}}}, []string{string(photo)}},
+		{"other namespace", MTOMOptions{Elements: []xml.Name{{Space: "urn:other", Local: "Photo"}}}, nil},
+		{"min size", MTOMOptions{MinSize: 100}, []string{string(blob)}},
+		{"both", MTOMOptions{Elements: []xml.Name{{Local: "Photo"}}, MinSize: 100}, []string{string(photo), string(blob)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr, err := OptimizeMTOM(SOAP12, strings.NewReader(envelope), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			parts := readParts(t, mr)
+			if len(parts) != len(tt.parts)+1 {
+				t.Fatalf("got %d parts, want %d", len(parts), len(tt.parts)+1)
+			}
+			root := parts[0].Body
+			if !strings.Contains(root, "<m:Name>abcd</m:Name>") {
+				t.Errorf("envelope is not kept:\n%s", root)
+			}
+			for i, want := range tt.parts {
+				a := parts[i+1]
+				if a.Body != want {
+					t.Errorf("attachment %d is %q, want %q", i, a.Body, want)
+				}
+				cid := strings.Trim(a.Header.Get("Content-ID"), "<>")
+				if !strings.Contains(root, XOPInclude(cid)) {
+					t.Errorf("envelope does not reference attachment %d:\n%s", i, root)
+				}
+			}
+			if len(tt.parts) > 0 && tt.opts.Elements != nil {
+				if ct := parts[1].Header.Get("Content-Type"); ct != "image/png" {
+					t.Errorf("attachment content type is %q, want image/png", ct)
+				}
+			}
+			if strings.Count(root, "xop:Include") != len(tt.parts) {
+				t.Errorf("envelope has wrong number of includes:\n%s", root)
+			}
+		})
+	}
+}
+
+func TestOptimizeMTOMInvalidXML(t *testing.T) {
+	if _, err := OptimizeMTOM(SOAP11, strings.NewReader("<a><b></a>"), MTOMOptions{MinSize: 1}); err == nil {
+		t.Fatal("invalid envelope is accepted")
+	}
+}
+
+func TestNewMTOMEmptyContentID(t *testing.T) {
+	mr := NewMTOM(SOAP11, strings.NewReader("<e/>"), &MTOMAttachment{Body: strings.NewReader("data")})
+	if _, err := io.ReadAll(mr); !errors.Is(err, ErrNoContentID) {
+		t.Fatalf("got %v, want ErrNoContentID", err)
+	}
+}
@@ -7,14 +7,20 @@ package multipartreader
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"log/slog"
 	"mime/multipart"
 	"net/http"
-	"os"
+	"net/textproto"
+	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 	"sync/atomic"
+	"time"
 )
 
 // MultipartReader implements io.Reader, can be used to encode large files
@@ -26,6 +32,23 @@ type MultipartReader struct {
 	readers     []io.Reader
 	multiReader io.Reader
 	count       int64
+	parts       []*part
+	related     *related
+	inspector   Inspector
+
+	logger    *slog.Logger
+	logLevel  slog.Level
+	observers []Observer
+	stats     stats
+	debug     *debugTap
+
+	partLength   PartLengthMode
+	charsetField bool
+	streamed     bool // parts are also added while reading, see NewProxy
+	normalizeEOL bool
+	err          error
+	started      time.Time
+	finished     bool
 }
 
 // New creates new MultipartReader
@@ -49,9 +72,21 @@ func New() (mr *MultipartReader) {
 	return
 }
 
-// SetBoundary method is multipart.Writer.SetBoundary copy
+// SetBoundary method is multipart.Writer.SetBoundary copy,
+// must be called before any parts are added
 func (w *MultipartReader) SetBoundary(boundary string) (err error) {
-	err = w.writer.SetBoundary(boundary)
+	if len(w.parts) > 0 {
+		return errors.New("multipartreader: SetBoundary called after parts were added")
+	}
+	if err = w.writer.SetBoundary(boundary); err != nil {
+		return
+	}
+	w.boundary = boundary
+	w.contentType = w.writer.FormDataContentType()
+	if w.related != nil {
+		w.updateRelated()
+	}
+	w.readers[len(w.readers)-1] = strings.NewReader("\r\n--" + boundary + "--\r\n")
 	return
 }
 
@@ -61,56 +96,163 @@ func (mr *MultipartReader) AddReader(r io.Reader) {
 	mr.readers = append(mr.readers[:i-1], r, mr.readers[i-1])
 }
 
-// AddFormReader adds new reader as form part to MultipartReader
-func (mr *MultipartReader) AddFormReader(name, filename string, r io.Reader) (err error) {
-	var fw io.Writer
-	if fw, err = mr.writer.CreateFormFile(name, filename); err != nil {
-		return
+// AddPart adds new part with custom header to MultipartReader
+func (mr *MultipartReader) AddPart(header textproto.MIMEHeader, body io.Reader, opts ...PartOption) {
+	if mr.normalizeEOL && isText(header) {
+		body = &crlfReader{r: body}
 	}
-	if _, err = io.Copy(fw, r); err != nil {
-		return
+	if mr.partLength != PartLengthOff {
+		header = mr.setPartLength(header, body)
+	}
+	p := newPart(header, body, opts)
+	mr.addPart(p, bytes.NewReader(encodeHeader(header)))
+}
+
+// addPart adds part p, header is encoded header of p
+func (mr *MultipartReader) addPart(p *part, header io.Reader) {
+	for _, r := range mr.partReaders(p, header) {
+		mr.AddReader(r)
+	}
+}
+
+// partReaders registers part p and returns readers of its delimiter, header and body
+func (mr *MultipartReader) partReaders(p *part, header io.Reader) []io.Reader {
+	return mr.partReadersAt(len(mr.parts), p, header)
+}
+
+// partReadersAt is partReaders for part which is sent as i-th part,
+// indexes of parts after it are shifted
+func (mr *MultipartReader) partReadersAt(i int, p *part, header io.Reader) []io.Reader {
+	delimiter := strings.NewReader(mr.delimiter())
+	mr.parts = slices.Insert(mr.parts, i, p)
+	for j := i; j < len(mr.parts); j++ {
+		mr.parts[j].index = j
+	}
+	mr.partAdded(p)
+	return []io.Reader{delimiter, header, &partReader{mr: mr, p: p}}
+}
+
+// delimiter returns boundary line which opens next part
+func (mr *MultipartReader) delimiter() string {
+	if len(mr.parts) == 0 && !mr.streamed {
+		return "--" + mr.boundary + "\r\n"
 	}
+	return "\r\n--" + mr.boundary + "\r\n"
+}
+
+// encodeHeader encodes part header, keys are sorted to make output stable
+func encodeHeader(header textproto.MIMEHeader) []byte {
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b bytes.Buffer
+	for _, k := range keys {
+		for _, v := range header[k] {
+			fmt.Fprintf(&b, "%s: %s\r\n", k, v)
+		}
+	}
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
+
+// AddFormReader adds new reader as form file part to MultipartReader,
+// header is the one of multipart.Writer.CreateFormFile.
+// r is not copied here but read with the body, so it must stay open until mr is read
+func (mr *MultipartReader) AddFormReader(name, filename string, r io.Reader) (err error) {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", formatDisposition("form-data", map[string]string{
+		"name":     name,
+		"filename": filename,
+	}))
+	header.Set("Content-Type", "application/octet-stream")
+	mr.AddPart(header, r)
 	return
 }
 
 // https://stackoverflow.com/questions/20205796/post-data-using-the-content-type-multipart-form-data
 
-// WriteFields writes multiple form fields to the multipart.Writer.
+// WriteFields writes multiple form fields to the multipart.Writer,
+// fields are sorted by key so the body is deterministic.
 func (mr *MultipartReader) WriteFields(fields map[string]string) error {
-	for key, value := range fields {
-		form := fmt.Sprintf("--%s\r\nContent-Disposition: form-data; name=\"%s\"\r\n\r\n", mr.boundary, key)
-		mr.AddReader(strings.NewReader(form))
-		mr.AddReader(strings.NewReader(value + "\r\n"))
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := fields[key]
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", formatDisposition("form-data", map[string]string{"name": key}))
+		mr.AddPart(header, strings.NewReader(value))
 	}
 
 	return nil
 }
 
-// AddFile adds new file to MultipartReader
+// AddFile adds new file to MultipartReader, file is opened when its content is read
 func (mr *MultipartReader) WriteFile(key, filename string) (err error) {
-	fs, err := os.Open(filename)
+	fs, err := newFileSource(filename)
 	if err != nil {
 		return err
 	}
-	defer fs.Close()
 
-	form := fmt.Sprintf("--%s\r\nContent-Disposition: form-data; name=\"%s\"; filename=\"%s\"\r\n\r\n", mr.boundary, "file", fs.Name())
-	mr.AddReader(strings.NewReader(form))
-	mr.AddReader(fs)
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", formatDisposition("form-data", map[string]string{
+		"name":     key,
+		"filename": filepath.Base(filename),
+	}))
+	mr.AddPart(header, fs)
 	return
 }
 
+// RequestOption configures request in SetupRequest
+type RequestOption func(req *http.Request)
+
+// WithExpectContinue sets Expect: 100-continue, body and lazy part sources
+// are not opened until the server agrees to receive it.
+// http.Transport must have ExpectContinueTimeout set, as http.DefaultTransport does
+func WithExpectContinue() RequestOption {
+	return func(req *http.Request) {
+		req.Header.Set("Expect", "100-continue")
+	}
+}
+
 // SetupHTTPRequest set multiReader and headers after adding readers
-func (mr *MultipartReader) SetupRequest(req *http.Request) {
+func (mr *MultipartReader) SetupRequest(req *http.Request, opts ...RequestOption) {
 	req.Body = mr.GetCloseReader()
 	req.Header.Add("Content-Type", mr.contentType)
+	for _, opt := range opts {
+		opt(req)
+	}
 }
 
 // Read implements the Read method
 func (mpr *MultipartReader) Read(p []byte) (n int, err error) {
+	if mpr.err != nil {
+		return 0, mpr.err
+	}
+	if mpr.started.IsZero() {
+		mpr.started = time.Now()
+		mpr.bodyStarted()
+	}
 	mr := mpr.GetMultiReader()
 	n, err = mr.Read(p)
 	atomic.AddInt64(&mpr.count, int64(n))
+	if n > 0 {
+		mpr.bytesRead(n)
+	}
+	if err != nil && !mpr.finished {
+		mpr.finished = true
+		if err == io.EOF {
+			mpr.bodyFinished(nil)
+		} else {
+			mpr.bodyFinished(err)
+		}
+	}
 	return n, err
 }
 
@@ -130,12 +272,17 @@ func (mr *MultipartReader) ContentType() string {
 
 func (mr *MultipartReader) GetMultiReader() io.Reader {
 	if mr.multiReader == nil {
-		mr.multiReader = io.MultiReader(mr.readers...)
+		readers := mr.readers
+		if mr.debug != nil {
+			readers = mr.debug.wrap(readers)
+		}
+		mr.multiReader = io.MultiReader(readers...)
 	}
 	return mr.multiReader
 }
 
+// GetCloseReader returns mr as io.ReadCloser, reads go through Read
+// so Count, Stats, logger and observers see them
 func (mr *MultipartReader) GetCloseReader() io.ReadCloser {
-	reader := mr.GetMultiReader()
-	return ioutil.NopCloser(reader)
+	return ioutil.NopCloser(mr)
 }
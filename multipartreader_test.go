@@ -0,0 +1,165 @@
+package multipartreader
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testPart is part decoded by mime/multipart
+type testPart struct {
+	Header textproto.MIMEHeader
+	Body   string
+}
+
+// readParts reads whole mr and decodes it with mime/multipart
+func readParts(t *testing.T, mr *MultipartReader) []testPart {
+	t.Helper()
+	body, err := io.ReadAll(mr)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return parseParts(t, mr.ContentType(), body)
+}
+
+// parseParts decodes body with mime/multipart, parts are not decoded
+func parseParts(t *testing.T, contentType string, body []byte) (parts []testPart) {
+	t.Helper()
+	r := multipart.NewReader(bytes.NewReader(body), boundaryOf(t, contentType))
+	for {
+		p, err := r.NextRawPart()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			t.Fatalf("part %d: %v\n%s", len(parts), err, body)
+		}
+		content, err := io.ReadAll(p)
+		if err != nil {
+			t.Fatalf("part %d: %v", len(parts), err)
+		}
+		parts = append(parts, testPart{Header: p.Header, Body: string(content)})
+	}
+}
+
+// boundaryOf returns boundary parameter of contentType
+func boundaryOf(t testing.TB, contentType string) string {
+	t.Helper()
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		t.Fatalf("content type %q: %v", contentType, err)
+	}
+	return params["boundary"]
+}
+
+// formBody encodes fields and files with multipart.Writer, fields go first
+func formBody(t testing.TB, fields [][2]string, files [][3]string) (contentType string, body []byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for _, f := range fields {
+		w.WriteField(f[0], f[1])
+	}
+	for _, f := range files {
+		fw, err := w.CreateFormFile(f[0], f[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(f[2]))
+	}
+	w.Close()
+	return w.FormDataContentType(), buf.Bytes()
+}
+
+func TestWriteFields(t *testing.T) {
+	mr := New()
+	mr.WriteFields(map[string]string{"b": "2", "a": "1"})
+
+	parts := readParts(t, mr)
+	if len(parts) != 2 {
+		t.Fatalf("got %d parts, want 2", len(parts))
+	}
+	for i, want := range []string{"1", "2"} {
+		if parts[i].Body != want {
+			t.Errorf("part %d is %q, want %q", i, parts[i].Body, want)
+		}
+	}
+}
+
+func TestAddFormReaderAfterFields(t *testing.T) {
+	mr := New()
+	mr.WriteFields(map[string]string{"a": "1"})
+	mr.AddFormReader("file", "x.txt", strings.NewReader("data"))
+	mr.WriteFields(map[string]string{"b": "2"})
+
+	parts := readParts(t, mr)
+	want := []struct{ disposition, body string }{
+		{`form-data; name="a"`, "1"},
+		{`form-data; name="file"; filename="x.txt"`, "data"},
+		{`form-data; name="b"`, "2"},
+	}
+	if len(parts) != len(want) {
+		t.Fatalf("got %d parts, want %d: %v", len(parts), len(want), parts)
+	}
+	for i, w := range want {
+		if got := parts[i].Header.Get("Content-Disposition"); got != w.disposition {
+			t.Errorf("part %d disposition is %q, want %q", i, got, w.disposition)
+		}
+		if parts[i].Body != w.body {
+			t.Errorf("part %d body is %q, want %q", i, parts[i].Body, w.body)
+		}
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.txt")
+	if err := os.WriteFile(path, []byte("content"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	mr := New()
+	if err := mr.WriteFile("attachment", path); err != nil {
+		t.Fatal(err)
+	}
+
+	parts := readParts(t, mr)
+	if len(parts) != 1 || parts[0].Body != "content" {
+		t.Fatalf("got %v", parts)
+	}
+	if got, want := parts[0].Header.Get("Content-Disposition"), `form-data; name="attachment"; filename="report.txt"`; got != want {
+		t.Errorf("disposition is %q, want %q", got, want)
+	}
+}
+
+func TestQuotedNames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), `say "hi".txt`)
+	if err := os.WriteFile(path, []byte("content"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	mr := New()
+	mr.WriteFields(map[string]string{`a"b\c`: "1"})
+	if err := mr.WriteFile(`f"ile`, path); err != nil {
+		t.Fatal(err)
+	}
+	mr.AddFormReader(`r"eader`, `x"y.txt`, strings.NewReader("data"))
+	body, err := io.ReadAll(mr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := multipart.NewReader(bytes.NewReader(body), mr.Boundary())
+	for _, want := range [][2]string{{`a"b\c`, ""}, {`f"ile`, `say "hi".txt`}, {`r"eader`, `x"y.txt`}} {
+		p, err := r.NextPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.FormName() != want[0] || p.FileName() != want[1] {
+			t.Errorf("got name %q, filename %q, want %q, %q", p.FormName(), p.FileName(), want[0], want[1])
+		}
+	}
+}
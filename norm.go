@@ -0,0 +1,132 @@
+package multipartreader
+
+import "sort"
+
+// Hangul syllable constants of Unicode chapter 3.12
+const (
+	hangulS      = 0xAC00
+	hangulL      = 0x1100
+	hangulV      = 0x1161
+	hangulT      = 0x11A7
+	hangulLCount = 19
+	hangulVCount = 21
+	hangulTCount = 28
+	hangulNCount = hangulVCount * hangulTCount
+	hangulSCount = hangulLCount * hangulNCount
+)
+
+// nfc returns s in Unicode Normalization Form C (UAX #15),
+// s must be valid UTF-8
+func nfc(s string) string {
+	quick := true
+	for _, r := range s {
+		if r >= 0x300 {
+			quick = false
+			break
+		}
+	}
+	if quick {
+		// nothing below U+0300 decomposes or combines
+		return s
+	}
+
+	var runes []rune
+	for _, r := range s {
+		runes = decompose(runes, r)
+	}
+	reorder(runes)
+	return string(compose(runes))
+}
+
+// decompose appends full canonical decomposition of r to runes
+func decompose(runes []rune, r rune) []rune {
+	if r >= hangulS && r < hangulS+hangulSCount {
+		i := r - hangulS
+		runes = append(runes, hangulL+i/hangulNCount, hangulV+i%hangulNCount/hangulTCount)
+		if t := i % hangulTCount; t > 0 {
+			runes = append(runes, hangulT+t)
+		}
+		return runes
+	}
+	d, ok := decompositions[r]
+	if !ok {
+		return append(runes, r)
+	}
+	runes = decompose(runes, d[0])
+	if d[1] != 0 {
+		runes = decompose(runes, d[1])
+	}
+	return runes
+}
+
+// reorder sorts runs of combining marks by their combining class
+func reorder(runes []rune) {
+	for i := 0; i < len(runes); {
+		if combiningClass(runes[i]) == 0 {
+			i++
+			continue
+		}
+		j := i
+		for j < len(runes) && combiningClass(runes[j]) != 0 {
+			j++
+		}
+		marks := runes[i:j]
+		sort.SliceStable(marks, func(a, b int) bool {
+			return combiningClass(marks[a]) < combiningClass(marks[b])
+		})
+		i = j
+	}
+}
+
+// compose applies canonical composition to decomposed and reordered runes
+func compose(runes []rune) []rune {
+	if len(runes) == 0 {
+		return runes
+	}
+	out := runes[:1]
+	starter := 0
+	last := int(combiningClass(runes[0]))
+	if last != 0 {
+		// leading mark has no starter to combine with
+		starter, last = -1, 256
+	}
+	for _, r := range runes[1:] {
+		ccc := int(combiningClass(r))
+		if starter >= 0 && (last < ccc || last == 0) {
+			if c, ok := composePair(out[starter], r); ok {
+				out[starter] = c
+				continue
+			}
+		}
+		if ccc == 0 {
+			starter = len(out)
+		}
+		last = ccc
+		out = append(out, r)
+	}
+	return out
+}
+
+// composePair returns primary composite of a and b
+func composePair(a, b rune) (rune, bool) {
+	if a >= hangulL && a < hangulL+hangulLCount && b >= hangulV && b < hangulV+hangulVCount {
+		return hangulS + ((a-hangulL)*hangulVCount+b-hangulV)*hangulTCount, true
+	}
+	if a >= hangulS && a < hangulS+hangulSCount && (a-hangulS)%hangulTCount == 0 &&
+		b > hangulT && b < hangulT+hangulTCount {
+		return a + b - hangulT, true
+	}
+	c, ok := compositions[[2]rune{a, b}]
+	return c, ok
+}
+
+// combiningClass returns canonical combining class of r
+func combiningClass(r rune) uint8 {
+	i := sort.Search(len(combiningClasses), func(i int) bool {
+		return combiningClasses[i].hi >= r
+	})
+	if i < len(combiningClasses) && combiningClasses[i].lo <= r {
+		return combiningClasses[i].ccc
+	}
+	return 0
+}
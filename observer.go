@@ -0,0 +1,164 @@
+package multipartreader
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"net/textproto"
+	"time"
+)
+
+// PartInfo describes part for Observer, content is not included
+type PartInfo struct {
+	Index    int
+	Header   textproto.MIMEHeader
+	Size     int64
+	Duration time.Duration
+}
+
+// Observer receives lifecycle events of MultipartReader, e.g. for metrics and tracing
+type Observer interface {
+	BodyStart()
+	BodyFinish(bytes int64, err error)
+	PartStart(p PartInfo)
+	PartFinish(p PartInfo, err error)
+	BytesRead(n int)
+	Retry(attempt int, err error)
+}
+
+// AddObserver adds new Observer to MultipartReader
+func (mr *MultipartReader) AddObserver(o Observer) {
+	mr.observers = append(mr.observers, o)
+}
+
+// ExpvarObserver is Observer which publishes counters with expvar
+type ExpvarObserver struct {
+	m *expvar.Map
+}
+
+// NewExpvarObserver creates new ExpvarObserver publishing map name,
+// existing map with the same name is reused. It fails if name is
+// already published with other var than *expvar.Map
+func NewExpvarObserver(name string) (*ExpvarObserver, error) {
+	switch v := expvar.Get(name).(type) {
+	case nil:
+		return &ExpvarObserver{m: expvar.NewMap(name)}, nil
+	case *expvar.Map:
+		return &ExpvarObserver{m: v}, nil
+	default:
+		return nil, fmt.Errorf("multipartreader: expvar %q is %T, not *expvar.Map", name, v)
+	}
+}
+
+func (o *ExpvarObserver) BodyStart() {
+	o.m.Add("bodies_started", 1)
+}
+
+func (o *ExpvarObserver) BodyFinish(bytes int64, err error) {
+	if err != nil {
+		o.m.Add("bodies_failed", 1)
+		return
+	}
+	o.m.Add("bodies_finished", 1)
+}
+
+func (o *ExpvarObserver) PartStart(p PartInfo) {
+	o.m.Add("parts_started", 1)
+}
+
+func (o *ExpvarObserver) PartFinish(p PartInfo, err error) {
+	if err != nil {
+		o.m.Add("parts_failed", 1)
+		return
+	}
+	o.m.Add("parts_finished", 1)
+	o.m.Add("part_nanoseconds", int64(p.Duration))
+}
+
+func (o *ExpvarObserver) BytesRead(n int) {
+	o.m.Add("bytes_read", int64(n))
+}
+
+func (o *ExpvarObserver) Retry(attempt int, err error) {
+	o.m.Add("retries", 1)
+}
+
+// Attribute is key-value pair attached to Span
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// Span is subset of OpenTelemetry trace.Span used by TracingObserver
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	AddEvent(name string, attrs ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// Tracer is subset of OpenTelemetry trace.Tracer used by TracingObserver
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// TracingObserver is Observer which records body and its parts as spans,
+// part spans are children of the body span
+type TracingObserver struct {
+	parent  context.Context
+	tracer  Tracer
+	bodyCtx context.Context
+	body    Span
+	part    Span
+}
+
+// NewTracingObserver creates new TracingObserver, ctx is parent of body spans,
+// observer can be reused for bodies read one after another
+func NewTracingObserver(ctx context.Context, t Tracer) *TracingObserver {
+	return &TracingObserver{parent: ctx, tracer: t}
+}
+
+func (o *TracingObserver) BodyStart() {
+	o.bodyCtx, o.body = o.tracer.Start(o.parent, "multipart.body")
+}
+
+func (o *TracingObserver) BodyFinish(bytes int64, err error) {
+	if o.body == nil {
+		return
+	}
+	o.body.SetAttributes(Attribute{"multipart.bytes", bytes})
+	if err != nil {
+		o.body.RecordError(err)
+	}
+	o.body.End()
+	o.bodyCtx, o.body = nil, nil
+}
+
+func (o *TracingObserver) PartStart(p PartInfo) {
+	ctx := o.bodyCtx
+	if ctx == nil {
+		ctx = o.parent
+	}
+	_, o.part = o.tracer.Start(ctx, "multipart.part")
+	o.part.SetAttributes(Attribute{"multipart.part.index", p.Index})
+}
+
+func (o *TracingObserver) PartFinish(p PartInfo, err error) {
+	if o.part == nil {
+		return
+	}
+	o.part.SetAttributes(Attribute{"multipart.part.size", p.Size})
+	if err != nil {
+		o.part.RecordError(err)
+	}
+	o.part.End()
+	o.part = nil
+}
+
+func (o *TracingObserver) BytesRead(n int) {}
+
+func (o *TracingObserver) Retry(attempt int, err error) {
+	if o.body != nil {
+		o.body.AddEvent("multipart.retry", Attribute{"attempt", attempt}, Attribute{"error", err.Error()})
+	}
+}
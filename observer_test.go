@@ -0,0 +1,214 @@
+package multipartreader
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+)
+
+// recordedSpan is Span of recordingTracer
+type recordedSpan struct {
+	name   string
+	parent *recordedSpan
+	attrs  map[string]any
+	events []string
+	errs   []error
+	ended  bool
+}
+
+func (s *recordedSpan) SetAttributes(attrs ...Attribute) {
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *recordedSpan) AddEvent(name string, attrs ...Attribute) { s.events = append(s.events, name) }
+func (s *recordedSpan) RecordError(err error)                    { s.errs = append(s.errs, err) }
+func (s *recordedSpan) End()                                     { s.ended = true }
+
+type spanKey struct{}
+
+// recordingTracer is Tracer which records started spans and their parents
+type recordingTracer struct {
+	spans []*recordedSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	parent, _ := ctx.Value(spanKey{}).(*recordedSpan)
+	s := &recordedSpan{name: name, parent: parent, attrs: map[string]any{}}
+	t.spans = append(t.spans, s)
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+func TestTracingObserver(t *testing.T) {
+	tracer := &recordingTracer{}
+	root := &recordedSpan{name: "request"}
+	o := NewTracingObserver(context.WithValue(context.Background(), spanKey{}, root), tracer)
+
+	// the same observer traces two bodies
+	for i := 0; i < 2; i++ {
+		mr := New()
+		mr.AddObserver(o)
+		mr.WriteFields(map[string]string{"a": "1", "b": "22"})
+		if _, err := io.ReadAll(mr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(tracer.spans) != 6 {
+		t.Fatalf("got %d spans, want 6", len(tracer.spans))
+	}
+	for i, s := range tracer.spans {
+		if !s.ended {
+			t.Errorf("span %d %s is not ended", i, s.name)
+		}
+		switch i % 3 {
+		case 0:
+			if s.name != "multipart.body" || s.parent != root {
+				t.Errorf("span %d is %s with parent %v, want body span of request", i, s.name, s.parent)
+			}
+			if s.attrs["multipart.bytes"] == nil {
+				t.Errorf("body span %d has no size", i)
+			}
+		default:
+			body := tracer.spans[i-i%3]
+			if s.name != "multipart.part" || s.parent != body {
+				t.Errorf("span %d is %s, want part span of span %d", i, s.name, i-i%3)
+			}
+			if s.attrs["multipart.part.index"] != i%3-1 {
+				t.Errorf("span %d has part index %v", i, s.attrs["multipart.part.index"])
+			}
+		}
+	}
+}
+
+func TestTracingObserverError(t *testing.T) {
+	tracer := &recordingTracer{}
+	mr := New()
+	mr.AddObserver(NewTracingObserver(context.Background(), tracer))
+	broken := errors.New("broken")
+	mr.AddPart(textproto.MIMEHeader{}, io.MultiReader(strings.NewReader("x"), iotest.ErrReader(broken)))
+
+	if _, err := io.ReadAll(mr); !errors.Is(err, broken) {
+		t.Fatalf("got %v", err)
+	}
+	if len(tracer.spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(tracer.spans))
+	}
+	for _, s := range tracer.spans {
+		if !s.ended || len(s.errs) != 1 || !errors.Is(s.errs[0], broken) {
+			t.Errorf("span %s: ended %v, errors %v", s.name, s.ended, s.errs)
+		}
+	}
+}
+
+// countingObserver counts events, it is called from transport goroutines
+type countingObserver struct {
+	mu                       sync.Mutex
+	bodyStarts, bodyFinishes int
+	partStarts, partFinishes int
+	bytesRead, finishBytes   int64
+	err                      error
+}
+
+func (o *countingObserver) BodyStart() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.bodyStarts++
+}
+
+func (o *countingObserver) BodyFinish(bytes int64, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.bodyFinishes++
+	o.finishBytes = bytes
+	o.err = err
+}
+
+func (o *countingObserver) PartStart(p PartInfo) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.partStarts++
+}
+
+func (o *countingObserver) PartFinish(p PartInfo, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.partFinishes++
+}
+
+func (o *countingObserver) BytesRead(n int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.bytesRead += int64(n)
+}
+
+func (o *countingObserver) Retry(attempt int, err error) {}
+
+func TestObserverSetupRequest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+	}))
+	defer srv.Close()
+
+	o := &countingObserver{}
+	// map is not published, so counters start from zero with -count
+	ev := &ExpvarObserver{m: new(expvar.Map)}
+	mr := New()
+	mr.AddObserver(o)
+	mr.AddObserver(ev)
+	mr.WriteFields(map[string]string{"a": "1", "b": strings.Repeat("x", 100000)})
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr.SetupRequest(req)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.bodyStarts != 1 || o.bodyFinishes != 1 || o.err != nil {
+		t.Errorf("body started %d times, finished %d times with %v", o.bodyStarts, o.bodyFinishes, o.err)
+	}
+	if o.partStarts != 2 || o.partFinishes != 2 {
+		t.Errorf("parts started %d times, finished %d times, want 2", o.partStarts, o.partFinishes)
+	}
+	if o.bytesRead != mr.Count() || o.finishBytes != mr.Count() || mr.Count() == 0 {
+		t.Errorf("observed %d bytes read, %d at finish, Count is %d", o.bytesRead, o.finishBytes, mr.Count())
+	}
+	for key, want := range map[string]int64{"bodies_started": 1, "bodies_finished": 1, "parts_finished": 2, "bytes_read": mr.Count()} {
+		if got := ev.m.Get(key); got == nil || got.(*expvar.Int).Value() != want {
+			t.Errorf("expvar %s is %v, want %d", key, got, want)
+		}
+	}
+}
+
+func TestNewExpvarObserverConflict(t *testing.T) {
+	if expvar.Get("multipartreader_test_conflict") == nil {
+		expvar.NewString("multipartreader_test_conflict")
+	}
+	if _, err := NewExpvarObserver("multipartreader_test_conflict"); err == nil {
+		t.Errorf("got no error for var which is not a map")
+	}
+	first, err := NewExpvarObserver("multipartreader_test_reused")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := NewExpvarObserver("multipartreader_test_reused")
+	if err != nil || second.m != first.m {
+		t.Errorf("map is not reused: %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package multipartreader
+
+import (
+	"io"
+	"net/textproto"
+	"sync/atomic"
+	"time"
+)
+
+// part is a single body part added with AddPart
+type part struct {
+	index  int
+	header textproto.MIMEHeader
+	body   io.Reader
+	// generatedID is set when Content-ID was generated by AddRelatedPart
+	generatedID bool
+
+	tees   []io.Writer
+	finish []func(err error) error
+
+	started time.Time
+	size    int64
+	done    bool
+}
+
+// PartOption configures part added with AddPart
+type PartOption func(p *part)
+
+func newPart(header textproto.MIMEHeader, body io.Reader, opts []PartOption) *part {
+	p := &part{header: header, body: body}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// partReader reads body of part and feeds its hooks
+type partReader struct {
+	mr *MultipartReader
+	p  *part
+
+	// held bytes are inspected, first ready of them can be released
+	held  []byte
+	ready int
+	eof   bool
+	// vetoed is InspectionError returned from every Read after veto
+	vetoed error
+}
+
+func (pr *partReader) Read(b []byte) (n int, err error) {
+	if pr.vetoed != nil {
+		return 0, pr.vetoed
+	}
+	p := pr.p
+	if p.started.IsZero() {
+		p.started = time.Now()
+		pr.mr.partStarted(p)
+		if pr.mr.inspector != nil {
+			if err = pr.mr.inspector.InspectHeader(p.header); err != nil {
+				err = pr.veto(err)
+			}
+		}
+	}
+	if err != nil {
+		// vetoed before reading
+	} else if pr.mr.inspector != nil {
+		n, err = pr.inspectRead(b)
+	} else {
+		n, err = p.body.Read(b)
+	}
+	atomic.AddInt64(&p.size, int64(n))
+	if n > 0 {
+		for _, w := range p.tees {
+			if _, werr := w.Write(b[:n]); werr != nil {
+				err = werr
+				break
+			}
+		}
+	}
+	if err != nil && !p.done {
+		p.done = true
+		ferr := err
+		if ferr == io.EOF {
+			ferr = nil
+		}
+		for _, f := range p.finish {
+			if fe := f(ferr); fe != nil && err == io.EOF {
+				err = fe
+			}
+		}
+		if err == io.EOF {
+			pr.mr.partFinished(p, nil)
+		} else {
+			pr.mr.partFinished(p, err)
+		}
+	}
+	return
+}
+
+// inspectRead reads body through Inspector, the last chunk is held back
+// until the inspector accepts the end of part
+func (pr *partReader) inspectRead(b []byte) (n int, err error) {
+	in := pr.mr.inspector
+	for pr.ready == 0 {
+		if pr.eof {
+			return 0, io.EOF
+		}
+		chunk := make([]byte, len(b))
+		rn, rerr := pr.p.body.Read(chunk)
+		if rn > 0 {
+			if err = in.InspectChunk(chunk[:rn]); err != nil {
+				return 0, pr.veto(err)
+			}
+			pr.ready = len(pr.held)
+			pr.held = append(pr.held, chunk[:rn]...)
+		}
+		if rerr == io.EOF {
+			if err = in.InspectEnd(); err != nil {
+				return 0, pr.veto(err)
+			}
+			pr.ready = len(pr.held)
+			pr.eof = true
+		} else if rerr != nil {
+			return 0, rerr
+		}
+	}
+	n = copy(b, pr.held[:pr.ready])
+	pr.held = pr.held[n:]
+	pr.ready -= n
+	return
+}
+
+// veto stops the part and the whole body, MultiReader would go on
+// with the next chunk or the next reader otherwise
+func (pr *partReader) veto(err error) error {
+	pr.vetoed = &InspectionError{Part: pr.p.index, Header: pr.p.header, Err: err}
+	if pr.mr.err == nil {
+		pr.mr.err = pr.vetoed
+	}
+	return pr.vetoed
+}
+
+// info returns PartInfo of p
+func (p *part) info() PartInfo {
+	info := PartInfo{Index: p.index, Header: p.header, Size: atomic.LoadInt64(&p.size)}
+	if !p.started.IsZero() {
+		info.Duration = time.Since(p.started)
+	}
+	return info
+}
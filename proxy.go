@@ -0,0 +1,196 @@
+package multipartreader
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/textproto"
+	"sort"
+	"strings"
+)
+
+// ProxyPart is decoded part passed through proxy rules
+type ProxyPart struct {
+	Header textproto.MIMEHeader
+	Body   io.Reader
+	// Drop removes the part from the output
+	Drop bool
+	// After are parts sent right after this one, even if it is dropped,
+	// rules are not applied to them
+	After []*ProxyPart
+}
+
+// Rule rewrites part in NewProxy, returned error fails reading of the output
+type Rule func(pp *ProxyPart) error
+
+// NewProxy creates new MultipartReader which re-encodes parts of d on the fly,
+// rules are applied to each part in order and only one part is in flight at a time.
+// Parts added with AddPart afterwards follow the proxied ones
+func NewProxy(d *Decoder, rules ...Rule) (mr *MultipartReader) {
+	mr = New()
+	// order of parts is unknown, so every delimiter starts with CRLF
+	// and the first one is preceded by empty preamble
+	mr.streamed = true
+	next := func() (*ProxyPart, error) {
+		p, err := d.NextPart()
+		if err != nil {
+			return nil, err
+		}
+		return &ProxyPart{Header: p.Header, Body: p}, nil
+	}
+	mr.AddReader(&proxyReader{mr: mr, next: next, rules: rules})
+	return
+}
+
+// proxyReader pulls parts from next as they are needed
+type proxyReader struct {
+	mr    *MultipartReader
+	next  func() (*ProxyPart, error)
+	rules []Rule
+
+	queue []*ProxyPart // parts ready to be sent
+	sent  int          // number of parts sent, they go before parts added with AddPart
+	cur   io.Reader
+	done  bool
+}
+
+func (pr *proxyReader) Read(b []byte) (n int, err error) {
+	for {
+		if pr.cur != nil {
+			n, err = pr.cur.Read(b)
+			if err != io.EOF {
+				return
+			}
+			pr.cur = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		if len(pr.queue) > 0 {
+			pr.cur = pr.send(pr.queue[0])
+			pr.queue = pr.queue[1:]
+			continue
+		}
+		if pr.done {
+			return 0, io.EOF
+		}
+
+		var pp *ProxyPart
+		if pp, err = pr.next(); err != nil {
+			if err == io.EOF {
+				pr.done = true
+				continue
+			}
+			return 0, err
+		}
+		body := pp.Body
+		for _, rule := range pr.rules {
+			if err = rule(pp); err != nil {
+				return 0, err
+			}
+			if pp.Drop {
+				break
+			}
+		}
+		if !pp.Drop {
+			if pp.Body != body {
+				// transformed content has another length
+				pp.Header.Del("Content-Length")
+			}
+			pr.queue = append(pr.queue, pp)
+		}
+		pr.queue = append(pr.queue, pp.After...)
+	}
+}
+
+// send registers pp as the next part on the wire and returns its reader
+func (pr *proxyReader) send(pp *ProxyPart) io.Reader {
+	p := newPart(pp.Header, pp.Body, nil)
+	readers := pr.mr.partReadersAt(pr.sent, p, bytes.NewReader(encodeHeader(pp.Header)))
+	pr.sent++
+	if pr.mr.debug != nil {
+		readers = pr.mr.debug.wrap(readers)
+	}
+	return io.MultiReader(readers...)
+}
+
+// FormName returns form name of the part
+func (pp *ProxyPart) FormName() string {
+	return dispositionParam(pp.Header, "name")
+}
+
+// DropField drops parts with form name
+func DropField(name string) Rule {
+	return func(pp *ProxyPart) error {
+		pp.Drop = pp.FormName() == name
+		return nil
+	}
+}
+
+// RenameField renames parts with form name from to name to
+func RenameField(from, to string) Rule {
+	return func(pp *ProxyPart) error {
+		if pp.FormName() != from {
+			return nil
+		}
+		disposition, params, err := mime.ParseMediaType(pp.Header.Get("Content-Disposition"))
+		if err != nil {
+			return err
+		}
+		params["name"] = to
+		pp.Header.Set("Content-Disposition", formatDisposition(disposition, params))
+		return nil
+	}
+}
+
+// InsertAfter sends part returned by add after parts with form name,
+// nil means nothing is inserted
+func InsertAfter(name string, add func(pp *ProxyPart) *ProxyPart) Rule {
+	return func(pp *ProxyPart) error {
+		if pp.FormName() != name {
+			return nil
+		}
+		if extra := add(pp); extra != nil {
+			pp.After = append(pp.After, extra)
+		}
+		return nil
+	}
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// formatDisposition formats Content-Disposition with quoted parameters
+// as multipart.Writer does, name and filename go first
+func formatDisposition(disposition string, params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		if k != "name" && k != "filename" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range []string{"filename", "name"} {
+		if _, ok := params[k]; ok {
+			keys = append([]string{k}, keys...)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(disposition)
+	for _, k := range keys {
+		fmt.Fprintf(&b, `; %s="%s"`, k, quoteEscaper.Replace(params[k]))
+	}
+	return b.String()
+}
+
+// TransformField wraps content of parts with form name, e.g. to strip EXIF of images
+func TransformField(name string, transform func(header textproto.MIMEHeader, body io.Reader) io.Reader) Rule {
+	return func(pp *ProxyPart) error {
+		if pp.FormName() == name {
+			pp.Body = transform(pp.Header, pp.Body)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,119 @@
+package multipartreader
+
+import (
+	"bytes"
+	"io"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+func newTestProxy(t *testing.T, rules ...Rule) *MultipartReader {
+	t.Helper()
+	ct, body := formBody(t,
+		[][2]string{{"a", "alpha"}, {"password", "hunter2"}, {"long", strings.Repeat("x", 100)}},
+		[][3]string{{"img", "photo.jpg", "image data"}},
+	)
+	d, err := NewDecoder(bytes.NewReader(body), ct, DecoderOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewProxy(d, rules...)
+}
+
+func TestProxyRules(t *testing.T) {
+	mr := newTestProxy(t,
+		DropField("password"),
+		RenameField("a", "b"),
+		TransformField("img", func(header textproto.MIMEHeader, body io.Reader) io.Reader {
+			return strings.NewReader("stripped")
+		}),
+		InsertAfter("img", func(pp *ProxyPart) *ProxyPart {
+			return &ProxyPart{
+				Header: textproto.MIMEHeader{"Content-Disposition": {`form-data; name="img-source"`}},
+				Body:   strings.NewReader(pp.Header.Get("Content-Disposition")),
+			}
+		}),
+	)
+	mr.WriteFields(map[string]string{"sig": "server"})
+
+	parts := readParts(t, mr)
+	want := []struct{ disposition, body string }{
+		{`form-data; name="b"`, "alpha"},
+		{`form-data; name="long"`, strings.Repeat("x", 100)},
+		{`form-data; name="img"; filename="photo.jpg"`, "stripped"},
+		{`form-data; name="img-source"`, `form-data; name="img"; filename="photo.jpg"`},
+		{`form-data; name="sig"`, "server"},
+	}
+	if len(parts) != len(want) {
+		t.Fatalf("got %d parts, want %d: %v", len(parts), len(want), parts)
+	}
+	for i, w := range want {
+		if got := parts[i].Header.Get("Content-Disposition"); got != w.disposition {
+			t.Errorf("part %d disposition is %q, want %q", i, got, w.disposition)
+		}
+		if parts[i].Body != w.body {
+			t.Errorf("part %d body is %q, want %q", i, parts[i].Body, w.body)
+		}
+	}
+}
+
+// indexObserver records indexes of started parts
+type indexObserver struct {
+	names   []string
+	indexes []int
+}
+
+func (o *indexObserver) BodyStart()                        {}
+func (o *indexObserver) BodyFinish(bytes int64, err error) {}
+func (o *indexObserver) BytesRead(n int)                   {}
+func (o *indexObserver) Retry(attempt int, err error)      {}
+func (o *indexObserver) PartFinish(p PartInfo, err error)  {}
+
+func (o *indexObserver) PartStart(p PartInfo) {
+	o.names = append(o.names, dispositionParam(p.Header, "name"))
+	o.indexes = append(o.indexes, p.Index)
+}
+
+func TestProxyIndexes(t *testing.T) {
+	mr := newTestProxy(t)
+	mr.WriteFields(map[string]string{"sig": "server"})
+	o := &indexObserver{}
+	mr.AddObserver(o)
+	readParts(t, mr)
+
+	names := []string{"a", "password", "long", "img", "sig"}
+	if strings.Join(o.names, ",") != strings.Join(names, ",") {
+		t.Fatalf("parts started in order %v, want %v", o.names, names)
+	}
+	for i, index := range o.indexes {
+		if index != i {
+			t.Errorf("part %q has index %d, want %d", o.names[i], index, i)
+		}
+	}
+	for i, p := range mr.Stats().Completed {
+		if p.Index != i {
+			t.Errorf("stats report part %d with index %d", i, p.Index)
+		}
+	}
+}
+
+func TestProxyDebug(t *testing.T) {
+	mr := newTestProxy(t)
+	var tap bytes.Buffer
+	mr.SetDebug(&tap, DebugOptions{MaxBody: 10, Redact: []string{"password"}})
+	readParts(t, mr)
+
+	out := tap.String()
+	if strings.Contains(out, "hunter2") {
+		t.Errorf("redacted field is in debug output:\n%s", out)
+	}
+	if strings.Contains(out, strings.Repeat("x", 11)) {
+		t.Errorf("long field is not truncated:\n%s", out)
+	}
+	for _, want := range []string{"[REDACTED 7 bytes]", "[... 90 bytes truncated]", `name="img"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("debug output has no %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package multipartreader
+
+import (
+	"bytes"
+	"io"
+)
+
+// lazyReader opens underlying reader on first Read
+type lazyReader struct {
+	open func() (io.Reader, error)
+	r    io.Reader
+	err  error
+}
+
+func (lr *lazyReader) Read(p []byte) (n int, err error) {
+	if lr.r == nil && lr.err == nil {
+		lr.r, lr.err = lr.open()
+	}
+	if lr.err != nil {
+		return 0, lr.err
+	}
+	return lr.r.Read(p)
+}
+
+// transformReader streams src through writer returned by wrap,
+// e.g. encrypting or encoding writer, without goroutines
+type transformReader struct {
+	src  io.Reader
+	wrap func(w io.Writer) (io.WriteCloser, error)
+
+	wc    io.WriteCloser
+	buf   bytes.Buffer
+	chunk []byte
+	done  bool
+	err   error
+}
+
+func (tr *transformReader) Read(p []byte) (n int, err error) {
+	if tr.wc == nil && tr.err == nil {
+		tr.wc, tr.err = tr.wrap(&tr.buf)
+	}
+	if tr.chunk == nil {
+		tr.chunk = make([]byte, 32*1024)
+	}
+	for tr.buf.Len() == 0 && !tr.done && tr.err == nil {
+		var rn int
+		rn, err = tr.src.Read(tr.chunk)
+		if rn > 0 {
+			if _, werr := tr.wc.Write(tr.chunk[:rn]); werr != nil {
+				tr.err = werr
+				break
+			}
+		}
+		if err == io.EOF {
+			tr.done = true
+			tr.err = tr.wc.Close()
+		} else if err != nil {
+			tr.err = err
+		}
+	}
+	if tr.buf.Len() > 0 {
+		return tr.buf.Read(p)
+	}
+	if tr.err != nil {
+		return 0, tr.err
+	}
+	return 0, io.EOF
+}
@@ -0,0 +1,117 @@
+package multipartreader
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"net/textproto"
+	"net/url"
+	"strings"
+)
+
+// ErrNoSuchContentID is returned when no part has requested Content-ID
+var ErrNoSuchContentID = errors.New("multipartreader: no part with such Content-ID")
+
+// related holds parameters of multipart/related body (RFC 2387)
+type related struct {
+	start  string
+	params map[string]string
+}
+
+// NewRelated creates new MultipartReader with multipart/related body,
+// first added part is the root until SetStart is called
+func NewRelated() (mr *MultipartReader) {
+	mr = New()
+	mr.related = &related{params: map[string]string{}}
+	mr.updateRelated()
+	return
+}
+
+// NewContentID generates unique Content-ID, without angle brackets
+func NewContentID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b[:]) + "@multipartreader"
+}
+
+// CIDURL returns cid: URL referencing part with Content-ID cid (RFC 2392)
+func CIDURL(cid string) string {
+	return "cid:" + url.PathEscape(cid)
+}
+
+// AddRelatedPart adds new part with Content-ID header to MultipartReader,
+// cid is generated if empty. Returns cid of the part
+func (mr *MultipartReader) AddRelatedPart(cid string, header textproto.MIMEHeader, r io.Reader, opts ...PartOption) string {
+	if cid == "" {
+		cid = NewContentID()
+		opts = append(opts, func(p *part) { p.generatedID = true })
+	}
+	if header == nil {
+		header = textproto.MIMEHeader{}
+	}
+	header.Set("Content-ID", "<"+cid+">")
+	mr.AddPart(header, r, opts...)
+	mr.updateRelated()
+	return cid
+}
+
+// SetStart selects root part of multipart/related body by its Content-ID
+func (mr *MultipartReader) SetStart(cid string) error {
+	if mr.related == nil {
+		mr.related = &related{params: map[string]string{}}
+	}
+	if mr.partByContentID(cid) == nil {
+		return ErrNoSuchContentID
+	}
+	mr.related.start = cid
+	mr.updateRelated()
+	return nil
+}
+
+// SetRelatedParam sets additional parameter of multipart/related Content-Type,
+// e.g. start-info
+func (mr *MultipartReader) SetRelatedParam(key, value string) {
+	if mr.related == nil {
+		mr.related = &related{params: map[string]string{}}
+	}
+	mr.related.params[key] = value
+	mr.updateRelated()
+}
+
+// partByContentID returns part with Content-ID cid or nil
+func (mr *MultipartReader) partByContentID(cid string) *part {
+	for _, p := range mr.parts {
+		if strings.Trim(p.header.Get("Content-ID"), "<>") == cid {
+			return p
+		}
+	}
+	return nil
+}
+
+// updateRelated rebuilds Content-Type of multipart/related body,
+// type parameter follows Content-Type of the root part
+func (mr *MultipartReader) updateRelated() {
+	params := map[string]string{"boundary": mr.boundary}
+	for k, v := range mr.related.params {
+		params[k] = v
+	}
+
+	var root *part
+	if mr.related.start != "" {
+		root = mr.partByContentID(mr.related.start)
+		params["start"] = "<" + mr.related.start + ">"
+	} else if len(mr.parts) > 0 {
+		root = mr.parts[0]
+	}
+	if root != nil && params["type"] == "" {
+		if mt, _, err := mime.ParseMediaType(root.header.Get("Content-Type")); err == nil {
+			params["type"] = mt
+		}
+	}
+
+	mr.contentType = mime.FormatMediaType("multipart/related", params)
+}
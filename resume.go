@@ -0,0 +1,206 @@
+package multipartreader
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotPersistable is returned by Manifest when a source can't be reopened later
+	ErrNotPersistable = errors.New("multipartreader: source can't be persisted")
+	// ErrSourceChanged is returned by FromManifest when a source file was modified
+	ErrSourceChanged = errors.New("multipartreader: source file changed")
+	// ErrNotSeekable is returned by Seek when body can't be positioned at offset
+	ErrNotSeekable = errors.New("multipartreader: body is not seekable")
+)
+
+// Manifest is persistent state of an upload, it is used to rebuild
+// identical MultipartReader in another process and resume from Offset
+type Manifest struct {
+	Boundary    string
+	ContentType string
+	Parts       []ManifestPart
+	// Offset is the last byte offset acknowledged by the server
+	Offset int64
+}
+
+// ManifestPart is part of Manifest, its content is either Data or file at Path
+type ManifestPart struct {
+	Header  textproto.MIMEHeader
+	Data    []byte    `json:",omitempty"`
+	Path    string    `json:",omitempty"`
+	Size    int64     `json:",omitempty"`
+	ModTime time.Time `json:",omitempty"`
+}
+
+// Manifest returns Manifest of MultipartReader, parts must be added with AddPart
+// and their bodies must be files or in-memory readers, ErrNotPersistable is returned otherwise
+func (mr *MultipartReader) Manifest() (m *Manifest, err error) {
+	if reason := mr.untracked(); reason != "" {
+		return nil, fmt.Errorf("%w: %s", ErrNotPersistable, reason)
+	}
+	m = &Manifest{Boundary: mr.boundary, ContentType: mr.contentType}
+	for _, p := range mr.parts {
+		mp := ManifestPart{Header: p.header}
+		switch body := p.body.(type) {
+		case *os.File:
+			var fi os.FileInfo
+			if fi, err = body.Stat(); err != nil {
+				return nil, err
+			}
+			if mp.Path, err = filepath.Abs(body.Name()); err != nil {
+				return nil, err
+			}
+			mp.Size = fi.Size()
+			mp.ModTime = fi.ModTime()
+		case *fileSource:
+			if mp.Path, err = filepath.Abs(body.path); err != nil {
+				return nil, err
+			}
+			mp.Size = body.size
+			mp.ModTime = body.modTime
+		case *bytes.Reader:
+			mp.Data = make([]byte, body.Size())
+			if _, err = body.ReadAt(mp.Data, 0); err != nil && err != io.EOF {
+				return nil, err
+			}
+		case *strings.Reader:
+			mp.Data = make([]byte, body.Size())
+			if _, err = body.ReadAt(mp.Data, 0); err != nil && err != io.EOF {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("%w: part %d has %T body", ErrNotPersistable, p.index, p.body)
+		}
+		m.Parts = append(m.Parts, mp)
+	}
+	return m, nil
+}
+
+// untracked tells why body has content which is not a part added with AddPart,
+// empty if there is none. Such content can't be described by parts
+func (mr *MultipartReader) untracked() string {
+	if mr.streamed {
+		return "parts are added while reading"
+	}
+	// readers are writer buffer, delimiter, header and body of each part, close reader
+	readers := mr.readers[1 : len(mr.readers)-1]
+	if n, _ := readerSize(mr.readers[0]); n > 0 || len(readers) != 3*len(mr.parts) {
+		return "content added with AddReader"
+	}
+	for i, p := range mr.parts {
+		if pr, ok := readers[3*i+2].(*partReader); !ok || pr.p != p {
+			return "content added with AddReader"
+		}
+	}
+	return ""
+}
+
+// Save writes Manifest to file, file is replaced atomically
+func (m *Manifest) Save(path string) (err error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return
+	}
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o600); err != nil {
+		return
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadManifest reads Manifest saved with Save
+func LoadManifest(path string) (m *Manifest, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	m = &Manifest{}
+	err = json.Unmarshal(data, m)
+	return
+}
+
+// FromManifest creates MultipartReader identical to the one Manifest was made of,
+// positioned at m.Offset. Returns ErrSourceChanged if any source file was modified
+func FromManifest(m *Manifest) (mr *MultipartReader, err error) {
+	mr = New()
+	if err = mr.SetBoundary(m.Boundary); err != nil {
+		return nil, err
+	}
+	mr.contentType = m.ContentType
+
+	for _, mp := range m.Parts {
+		if mp.Path == "" {
+			mr.AddPart(mp.Header, bytes.NewReader(mp.Data))
+			continue
+		}
+		var fs *fileSource
+		if fs, err = newFileSource(mp.Path); err != nil {
+			return nil, err
+		}
+		if fs.size != mp.Size || !fs.modTime.Equal(mp.ModTime) {
+			return nil, fmt.Errorf("%w: %s", ErrSourceChanged, mp.Path)
+		}
+		mr.AddPart(mp.Header, fs)
+	}
+
+	if m.Offset > 0 {
+		if _, err = mr.Seek(m.Offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+	return mr, nil
+}
+
+// Seek sets offset of the next Read, only io.SeekStart before the first Read is supported.
+// Skipped part bodies are not seen by tees, inspector and observers
+func (mr *MultipartReader) Seek(offset int64, whence int) (int64, error) {
+	if whence != io.SeekStart || mr.multiReader != nil {
+		return 0, ErrNotSeekable
+	}
+	left := offset
+	for _, r := range mr.readers {
+		if left == 0 {
+			break
+		}
+		size, ok := readerSize(r)
+		if !ok {
+			return 0, ErrNotSeekable
+		}
+		skip := size
+		if skip > left {
+			skip = left
+		}
+		if err := skipReader(r, skip); err != nil {
+			return 0, err
+		}
+		left -= skip
+	}
+	if left > 0 {
+		return 0, fmt.Errorf("multipartreader: offset %d is beyond the end of body", offset)
+	}
+	return offset, nil
+}
+
+// skipReader skips n bytes of r without reading them
+func skipReader(r io.Reader, n int64) error {
+	switch r := r.(type) {
+	case *partReader:
+		return skipReader(r.p.body, n)
+	case *bytes.Buffer:
+		r.Next(int(n))
+		return nil
+	case io.Seeker:
+		_, err := r.Seek(n, io.SeekCurrent)
+		return err
+	}
+	return ErrNotSeekable
+}
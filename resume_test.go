@@ -0,0 +1,140 @@
+package multipartreader
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newResumable creates body with in-memory and file parts
+func newResumable(t *testing.T, path string) *MultipartReader {
+	t.Helper()
+	mr := New()
+	mr.WriteFields(map[string]string{"a": "1", "b": strings.Repeat("b", 1000)})
+	if err := mr.WriteFile("file", path); err != nil {
+		t.Fatal(err)
+	}
+	return mr
+}
+
+func TestResumeFromManifest(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.bin")
+	if err := os.WriteFile(path, bytes.Repeat([]byte("0123456789"), 5000), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	mr := newResumable(t, path)
+	m, err := mr.Manifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	full, err := io.ReadAll(mr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifestPath := filepath.Join(dir, "upload.json")
+	for _, offset := range []int64{0, 1, 100, 1200, 1300, int64(len(full)) - 1, int64(len(full))} {
+		m.Offset = offset
+		if err = m.Save(manifestPath); err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := LoadManifest(manifestPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resumed, err := FromManifest(loaded)
+		if err != nil {
+			t.Fatalf("offset %d: %v", offset, err)
+		}
+		if resumed.ContentType() != mr.ContentType() {
+			t.Errorf("offset %d: content type is %q, want %q", offset, resumed.ContentType(), mr.ContentType())
+		}
+		rest, err := io.ReadAll(resumed)
+		if err != nil {
+			t.Fatalf("offset %d: %v", offset, err)
+		}
+		if !bytes.Equal(rest, full[offset:]) {
+			t.Errorf("offset %d: resumed body differs", offset)
+		}
+	}
+
+	m.Offset = int64(len(full)) + 1
+	if _, err = FromManifest(m); err == nil {
+		t.Errorf("offset beyond the end is accepted")
+	}
+
+	// modification time resolution of some filesystems is coarse
+	later := time.Now().Add(time.Hour)
+	if err = os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	m.Offset = 0
+	if _, err = FromManifest(m); !errors.Is(err, ErrSourceChanged) {
+		t.Errorf("got %v for modified file, want ErrSourceChanged", err)
+	}
+}
+
+func TestSeek(t *testing.T) {
+	build := func() *MultipartReader {
+		mr := New()
+		mr.SetBoundary("fixed")
+		mr.WriteFields(map[string]string{"a": "value"})
+		return mr
+	}
+	full, _ := io.ReadAll(build())
+
+	for _, offset := range []int64{0, 10, int64(len(full))} {
+		mr := build()
+		if _, err := mr.Seek(offset, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		rest, err := io.ReadAll(mr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(rest, full[offset:]) {
+			t.Errorf("body after Seek(%d) is %q, want %q", offset, rest, full[offset:])
+		}
+		if _, err = mr.Seek(0, io.SeekStart); !errors.Is(err, ErrNotSeekable) {
+			t.Errorf("Seek after Read returned %v, want ErrNotSeekable", err)
+		}
+	}
+}
+
+func TestManifestNotPersistable(t *testing.T) {
+	ct, body := formBody(t, [][2]string{{"a", "1"}}, nil)
+	readers := map[string]func() *MultipartReader{
+		"unknown body": func() *MultipartReader {
+			mr := New()
+			mr.AddPart(nil, io.MultiReader(strings.NewReader("x")))
+			return mr
+		},
+		"AddReader": func() *MultipartReader {
+			mr := New()
+			mr.WriteFields(map[string]string{"a": "1"})
+			mr.AddReader(strings.NewReader("raw content"))
+			return mr
+		},
+		"only AddReader": func() *MultipartReader {
+			mr := New()
+			mr.AddReader(strings.NewReader("raw content"))
+			return mr
+		},
+		"FromMultipartReader": func() *MultipartReader {
+			return FromMultipartReader(multipart.NewReader(bytes.NewReader(body), boundaryOf(t, ct)))
+		},
+	}
+	for name, newReader := range readers {
+		if _, err := newReader().Manifest(); !errors.Is(err, ErrNotPersistable) {
+			t.Errorf("%s: got %v, want ErrNotPersistable", name, err)
+		}
+	}
+}
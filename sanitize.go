@@ -0,0 +1,182 @@
+package multipartreader
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrUnsafeFilename is returned for client supplied filenames which can't be made safe
+var ErrUnsafeFilename = errors.New("multipartreader: unsafe filename")
+
+// DefaultMaxFilename is default limit of sanitized filename length in bytes
+const DefaultMaxFilename = 255
+
+// SanitizeOptions configures SanitizeFilename
+type SanitizeOptions struct {
+	// MaxLength is limit in bytes, DefaultMaxFilename if zero
+	MaxLength int
+	// Normalize replaces built-in NFC normalization, e.g. with norm.NFKC.String
+	// from golang.org/x/text/unicode/norm
+	Normalize func(string) string
+}
+
+// reservedNames are device names reserved on Windows
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// SanitizeFilename makes client supplied filename safe to store: name is normalized to NFC,
+// directory components and drive prefixes are stripped, reserved names are prefixed with underscore
+// and length is capped. Names with "..", NUL, control characters or invalid UTF-8 are rejected
+func SanitizeFilename(name string, opts SanitizeOptions) (string, error) {
+	if !utf8.ValidString(name) {
+		return "", fmt.Errorf("%w: invalid UTF-8", ErrUnsafeFilename)
+	}
+	if opts.Normalize != nil {
+		name = opts.Normalize(name)
+	} else {
+		name = nfc(name)
+	}
+	for _, r := range name {
+		if r == 0 || unicode.IsControl(r) {
+			return "", fmt.Errorf("%w: control character %q", ErrUnsafeFilename, r)
+		}
+	}
+
+	name = strings.ReplaceAll(name, "\\", "/")
+	if len(name) >= 2 && name[1] == ':' && isASCIILetter(name[0]) {
+		name = name[2:]
+	}
+	for _, elem := range strings.Split(name, "/") {
+		if elem == ".." {
+			return "", fmt.Errorf("%w: %q", ErrUnsafeFilename, "..")
+		}
+	}
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		name = name[i+1:]
+	}
+	// Windows drops trailing dots and spaces
+	name = strings.TrimRight(strings.TrimLeft(name, " "), ". ")
+	if name == "" {
+		return "", fmt.Errorf("%w: empty name", ErrUnsafeFilename)
+	}
+
+	stem := name
+	if i := strings.IndexByte(stem, '.'); i >= 0 {
+		stem = stem[:i]
+	}
+	if reservedNames[strings.ToUpper(strings.TrimSpace(stem))] {
+		name = "_" + name
+	}
+
+	max := opts.MaxLength
+	if max <= 0 {
+		max = DefaultMaxFilename
+	}
+	if len(name) > max {
+		ext := filepath.Ext(name)
+		if len(ext) >= max {
+			ext = ""
+		}
+		name = truncateUTF8(strings.TrimSuffix(name, ext), max-len(ext)) + ext
+	}
+	return name, nil
+}
+
+func isASCIILetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// truncateUTF8 cuts s to at most n bytes on rune boundary
+func truncateUTF8(s string, n int) string {
+	for n > 0 && n < len(s) && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// Store saves decoded files into Root directory, each file is written
+// to temporary file and renamed, so partial files are never visible.
+// Hard links are not used, so it works on FAT and network filesystems too
+type Store struct {
+	Root     string
+	Sanitize SanitizeOptions
+}
+
+// Save stores content of r under sanitized filename, existing files are not
+// overwritten, numeric suffix is added instead. Returns path of the stored file,
+// path is empty on error
+func (s *Store) Save(filename string, r io.Reader) (string, error) {
+	name, err := SanitizeFilename(filename, s.Sanitize)
+	if err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(s.Root, ".upload-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err = tmp.Close(); err != nil {
+		return "", err
+	}
+
+	path, err := s.reserve(name)
+	if err != nil {
+		return "", err
+	}
+	// rename replaces the empty reserved file atomically
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+// reserve creates empty file with name or with name with numeric suffix if it is taken,
+// O_EXCL makes it safe against concurrent Save
+func (s *Store) reserve(name string) (string, error) {
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	for n := 0; ; n++ {
+		path := filepath.Join(s.Root, name)
+		if n > 0 {
+			path = filepath.Join(s.Root, fmt.Sprintf("%s-%d%s", stem, n, ext))
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if err = f.Close(); err != nil {
+			os.Remove(path)
+			return "", err
+		}
+		return path, nil
+	}
+}
+
+// SaveTo stores content of part in st under its sanitized filename
+func (p *Part) SaveTo(st *Store) (string, error) {
+	return st.Save(p.FileName(), p)
+}
@@ -0,0 +1,109 @@
+package multipartreader
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+)
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := []struct {
+		name string
+		want string // empty if rejected
+	}{
+		{"photo.jpg", "photo.jpg"},
+		{"C:\\Users\\me\\photo.jpg", "photo.jpg"},
+		{"/etc/passwd", "passwd"},
+		{"../../etc/passwd", ""},
+		{"a\x00b", ""},
+		{"a\nb", ""},
+		{"\xff.txt", ""},
+		{"trail. . ", "trail"},
+		{"CON.txt", "_CON.txt"},
+		{"   ", ""},
+		{"cafe\u0301.txt", "caf\u00e9.txt"},
+		{"\u212b.txt", "\u00c5.txt"},
+		{"\u1100\u1161\u11a8", "\uac01"},
+		{strings.Repeat("a", 300) + ".txt", strings.Repeat("a", 251) + ".txt"},
+		{strings.Repeat("\u00e9", 200), strings.Repeat("\u00e9", 127)},
+	}
+	for _, tt := range tests {
+		got, err := SanitizeFilename(tt.name, SanitizeOptions{})
+		if tt.want == "" {
+			if !errors.Is(err, ErrUnsafeFilename) {
+				t.Errorf("%q: got %q, %v, want ErrUnsafeFilename", tt.name, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%q: got %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestSanitizeFilenameNormalize(t *testing.T) {
+	got, err := SanitizeFilename("cafe\u0301", SanitizeOptions{Normalize: strings.ToUpper})
+	if err != nil || got != "CAFE\u0301" {
+		t.Fatalf("got %q, %v, Normalize must replace NFC", got, err)
+	}
+}
+
+func TestStoreSave(t *testing.T) {
+	st := &Store{Root: t.TempDir()}
+	for i, want := range []string{"a.txt", "a-1.txt", "a-2.txt"} {
+		path, err := st.Save("dir/a.txt", strings.NewReader("content"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filepath.Base(path) != want {
+			t.Errorf("save %d stored %s, want %s", i, path, want)
+		}
+		if b, _ := os.ReadFile(path); string(b) != "content" {
+			t.Errorf("%s contains %q", path, b)
+		}
+	}
+
+	path, err := st.Save("b.txt", iotest.ErrReader(errors.New("broken")))
+	if err == nil || path != "" {
+		t.Errorf("failed save returned %q, %v", path, err)
+	}
+	entries, _ := os.ReadDir(st.Root)
+	if len(entries) != 3 {
+		t.Errorf("root has %d entries, want 3 stored files", len(entries))
+	}
+}
+
+func TestStoreSaveConcurrent(t *testing.T) {
+	st := &Store{Root: t.TempDir()}
+	const n = 20
+	paths := make(chan string, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			path, err := st.Save("same.txt", strings.NewReader("x"))
+			if err != nil {
+				t.Error(err)
+			}
+			paths <- path
+		}()
+	}
+	wg.Wait()
+	close(paths)
+
+	seen := map[string]bool{}
+	for path := range paths {
+		if seen[path] {
+			t.Errorf("%s is stored twice", path)
+		}
+		seen[path] = true
+	}
+	if entries, _ := os.ReadDir(st.Root); len(entries) != n {
+		t.Errorf("root has %d entries, want %d", len(entries), n)
+	}
+}
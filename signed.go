@@ -0,0 +1,81 @@
+package multipartreader
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/textproto"
+)
+
+// Signer computes detached signature of multipart/signed body (RFC 1847),
+// canonical bytes of the signed part are written to it while reading
+type Signer interface {
+	io.Writer
+
+	// Sign returns detached signature of all written bytes,
+	// already in its transfer form (e.g. ASCII-armored or base64)
+	Sign() ([]byte, error)
+
+	// Protocol returns protocol parameter, e.g. application/pgp-signature
+	Protocol() string
+
+	// Micalg returns micalg parameter, e.g. pgp-sha256
+	Micalg() string
+}
+
+// Encrypter encrypts content of multipart/encrypted body (RFC 1847)
+type Encrypter interface {
+	// Encrypt returns writer which writes encrypted data into w,
+	// Close must flush remaining data
+	Encrypt(w io.Writer) (io.WriteCloser, error)
+
+	// Protocol returns protocol parameter, e.g. application/pgp-encrypted
+	Protocol() string
+
+	// Control returns content of the control part, e.g. "Version: 1\r\n"
+	Control() []byte
+}
+
+// NewSigned creates new MultipartReader with multipart/signed body.
+// header and r are the signed part, r must be in canonical form (CRLF line endings),
+// signature is appended as the second part after r is read
+func NewSigned(s Signer, header textproto.MIMEHeader, r io.Reader) (mr *MultipartReader) {
+	mr = New()
+	mr.contentType = mime.FormatMediaType("multipart/signed", map[string]string{
+		"boundary": mr.boundary,
+		"protocol": s.Protocol(),
+		"micalg":   s.Micalg(),
+	})
+
+	// signed data is the whole first part, MIME headers included
+	p := newPart(header, r, []PartOption{WithTee(s)})
+	mr.addPart(p, io.TeeReader(bytes.NewReader(encodeHeader(header)), s))
+
+	mr.AddPart(textproto.MIMEHeader{"Content-Type": {s.Protocol()}}, &lazyReader{
+		open: func() (io.Reader, error) {
+			sig, err := s.Sign()
+			if err != nil {
+				return nil, err
+			}
+			return bytes.NewReader(sig), nil
+		},
+	})
+	return
+}
+
+// NewEncrypted creates new MultipartReader with multipart/encrypted body,
+// r is encrypted while reading
+func NewEncrypted(e Encrypter, r io.Reader) (mr *MultipartReader) {
+	mr = New()
+	mr.contentType = mime.FormatMediaType("multipart/encrypted", map[string]string{
+		"boundary": mr.boundary,
+		"protocol": e.Protocol(),
+	})
+
+	mr.AddPart(textproto.MIMEHeader{"Content-Type": {e.Protocol()}}, bytes.NewReader(e.Control()))
+	mr.AddPart(textproto.MIMEHeader{"Content-Type": {"application/octet-stream"}}, &transformReader{
+		src:  r,
+		wrap: e.Encrypt,
+	})
+	return
+}
@@ -0,0 +1,134 @@
+package multipartreader
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/sha512"
+	"encoding/base64"
+	"hash"
+	"io"
+	"mime"
+	"net/textproto"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// ed25519Signer is test Signer with Ed25519ph over SHA-512, signature is base64 encoded
+type ed25519Signer struct {
+	key  ed25519.PrivateKey
+	hash hash.Hash
+}
+
+func (s *ed25519Signer) Write(p []byte) (int, error) {
+	return s.hash.Write(p)
+}
+
+func (s *ed25519Signer) Sign() ([]byte, error) {
+	sig, err := s.key.Sign(nil, s.hash.Sum(nil), &ed25519.Options{Hash: crypto.SHA512})
+	if err != nil {
+		return nil, err
+	}
+	return []byte(base64.StdEncoding.EncodeToString(sig)), nil
+}
+
+func (s *ed25519Signer) Protocol() string { return "application/x-test-signature" }
+func (s *ed25519Signer) Micalg() string   { return "sha-512" }
+
+func TestNewSigned(t *testing.T) {
+	pub, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := textproto.MIMEHeader{"Content-Type": {"text/plain"}}
+	mr := NewSigned(&ed25519Signer{key: key, hash: sha512.New()}, header, strings.NewReader("signed\r\ntext"))
+	body, err := io.ReadAll(mr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(mr.ContentType())
+	if err != nil || mediaType != "multipart/signed" {
+		t.Fatalf("content type %q: %v", mr.ContentType(), err)
+	}
+	if params["protocol"] != "application/x-test-signature" || params["micalg"] != "sha-512" {
+		t.Errorf("content type parameters are %v", params)
+	}
+
+	// signed bytes are everything between the first two delimiters,
+	// CRLF before the delimiter belongs to it (RFC 1847)
+	first := []byte("--" + params["boundary"] + "\r\n")
+	next := []byte("\r\n--" + params["boundary"] + "\r\n")
+	if !bytes.HasPrefix(body, first) {
+		t.Fatalf("body does not start with delimiter:\n%s", body)
+	}
+	signed, rest, ok := bytes.Cut(body[len(first):], next)
+	if !ok {
+		t.Fatalf("no second part:\n%s", body)
+	}
+	if want := "Content-Type: text/plain\r\n\r\nsigned\r\ntext"; string(signed) != want {
+		t.Errorf("signed part is %q, want %q", signed, want)
+	}
+
+	parts := parseParts(t, mr.ContentType(), body)
+	if len(parts) != 2 {
+		t.Fatalf("got %d parts, want 2", len(parts))
+	}
+	sig, err := base64.StdEncoding.DecodeString(parts[1].Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha512.Sum512(signed)
+	if err = ed25519.VerifyWithOptions(pub, digest[:], sig, &ed25519.Options{Hash: crypto.SHA512}); err != nil {
+		t.Errorf("signature does not verify: %v", err)
+	}
+	if !bytes.Contains(rest, []byte(parts[1].Body)) {
+		t.Errorf("signature part is not after signed part")
+	}
+}
+
+// base64Encrypter is test Encrypter which only base64 encodes content
+type base64Encrypter struct{}
+
+func (base64Encrypter) Encrypt(w io.Writer) (io.WriteCloser, error) {
+	return base64.NewEncoder(base64.StdEncoding, w), nil
+}
+
+func (base64Encrypter) Protocol() string { return "application/x-test-encrypted" }
+func (base64Encrypter) Control() []byte  { return []byte("Version: 1\r\n") }
+
+func TestNewEncrypted(t *testing.T) {
+	// content spans several chunks of transformReader
+	content := strings.Repeat("secret content\r\n", 5000)
+	mr := NewEncrypted(base64Encrypter{}, iotest.HalfReader(strings.NewReader(content)))
+
+	mediaType, params, err := mime.ParseMediaType(mr.ContentType())
+	if err != nil || mediaType != "multipart/encrypted" {
+		t.Fatalf("content type %q: %v", mr.ContentType(), err)
+	}
+	if params["protocol"] != "application/x-test-encrypted" {
+		t.Errorf("protocol is %q", params["protocol"])
+	}
+
+	parts := readParts(t, mr)
+	if len(parts) != 2 {
+		t.Fatalf("got %d parts, want 2", len(parts))
+	}
+	if ct := parts[0].Header.Get("Content-Type"); ct != "application/x-test-encrypted" {
+		t.Errorf("control part has type %q", ct)
+	}
+	if parts[0].Body != "Version: 1\r\n" {
+		t.Errorf("control part is %q", parts[0].Body)
+	}
+	if ct := parts[1].Header.Get("Content-Type"); ct != "application/octet-stream" {
+		t.Errorf("encrypted part has type %q", ct)
+	}
+	decrypted, err := base64.StdEncoding.DecodeString(parts[1].Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decrypted) != content {
+		t.Errorf("decrypted part differs, got %d bytes, want %d", len(decrypted), len(content))
+	}
+}
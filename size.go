@@ -0,0 +1,112 @@
+package multipartreader
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// readerSize returns number of bytes left in r, ok is false if it is unknown
+func readerSize(r io.Reader) (n int64, ok bool) {
+	switch r := r.(type) {
+	case *bytes.Reader:
+		return int64(r.Len()), true
+	case *strings.Reader:
+		return int64(r.Len()), true
+	case *bytes.Buffer:
+		return int64(r.Len()), true
+	case *partReader:
+		return readerSize(r.p.body)
+	case *fileSource:
+		return r.size - r.pos, true
+	case *formFile:
+		return r.fh.Size - r.pos, true
+	case *crlfReader:
+		return r.size()
+	case *os.File:
+		fi, err := r.Stat()
+		if err != nil || !fi.Mode().IsRegular() {
+			return 0, false
+		}
+		pos, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, false
+		}
+		return fi.Size() - pos, true
+	case *io.SectionReader:
+		pos, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, false
+		}
+		return r.Size() - pos, true
+	case *io.LimitedReader:
+		if n, ok = readerSize(r.R); ok && n > r.N {
+			n = r.N
+		}
+		return
+	}
+	return 0, false
+}
+
+// Len returns length of the whole body which is left to read,
+// ok is false if size of any reader is unknown
+func (mr *MultipartReader) Len() (n int64, ok bool) {
+	for _, r := range mr.readers {
+		size, ok := readerSize(r)
+		if !ok {
+			return 0, false
+		}
+		n += size
+	}
+	return n, true
+}
+
+// PartLengthMode controls Content-Length headers of parts
+type PartLengthMode int
+
+const (
+	// PartLengthOff emits no Content-Length headers in parts
+	PartLengthOff PartLengthMode = iota
+	// PartLengthKnown emits Content-Length in parts whose size is known
+	PartLengthKnown
+	// PartLengthStrict emits Content-Length in every part,
+	// Read fails with *UnknownSizeError if size of any part is unknown
+	PartLengthStrict
+)
+
+// UnknownSizeError is returned from Read by PartLengthStrict mode
+type UnknownSizeError struct {
+	Part   int
+	Header textproto.MIMEHeader
+}
+
+func (e *UnknownSizeError) Error() string {
+	return fmt.Sprintf("multipartreader: part %d has unknown size, Content-Length required", e.Part)
+}
+
+// SetPartContentLength sets mode of Content-Length headers in parts,
+// applies to parts added with AddPart afterwards
+func (mr *MultipartReader) SetPartContentLength(mode PartLengthMode) {
+	mr.partLength = mode
+}
+
+// setPartLength returns copy of header with Content-Length of body
+func (mr *MultipartReader) setPartLength(header textproto.MIMEHeader, body io.Reader) textproto.MIMEHeader {
+	size, ok := readerSize(body)
+	if !ok {
+		if mr.partLength == PartLengthStrict && mr.err == nil {
+			mr.err = &UnknownSizeError{Part: len(mr.parts), Header: header}
+		}
+		return header
+	}
+	clone := textproto.MIMEHeader{}
+	for k, v := range header {
+		clone[k] = v
+	}
+	clone.Set("Content-Length", strconv.FormatInt(size, 10))
+	return clone
+}
@@ -0,0 +1,79 @@
+package multipartreader
+
+import (
+	"errors"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPartContentLength(t *testing.T) {
+	tests := []struct {
+		mode    PartLengthMode
+		lengths []string // Content-Length of known and unknown part
+		failed  bool
+	}{
+		{PartLengthOff, []string{"", ""}, false},
+		{PartLengthKnown, []string{"5", ""}, false},
+		{PartLengthStrict, nil, true},
+	}
+	for _, tt := range tests {
+		mr := New()
+		mr.SetPartContentLength(tt.mode)
+		mr.AddPart(textproto.MIMEHeader{"Content-Disposition": {`form-data; name="known"`}}, strings.NewReader("known"))
+		mr.AddPart(textproto.MIMEHeader{"Content-Disposition": {`form-data; name="unknown"`}}, io.MultiReader(strings.NewReader("unknown")))
+
+		if tt.failed {
+			var ue *UnknownSizeError
+			if _, err := io.ReadAll(mr); !errors.As(err, &ue) || ue.Part != 1 {
+				t.Errorf("mode %d: got %v, want UnknownSizeError of part 1", tt.mode, err)
+			}
+			continue
+		}
+		parts := readParts(t, mr)
+		for i, want := range tt.lengths {
+			if got := parts[i].Header.Get("Content-Length"); got != want {
+				t.Errorf("mode %d: part %d has Content-Length %q, want %q", tt.mode, i, got, want)
+			}
+		}
+	}
+}
+
+func TestPartContentLengthStrict(t *testing.T) {
+	mr := New()
+	mr.SetPartContentLength(PartLengthStrict)
+	mr.WriteFields(map[string]string{"a": "1", "b": "22"})
+	size, ok := mr.Len()
+
+	parts := readParts(t, mr)
+	for i, p := range parts {
+		if got := p.Header.Get("Content-Length"); got != strconv.Itoa(len(p.Body)) {
+			t.Errorf("part %d has Content-Length %q, content is %d bytes", i, got, len(p.Body))
+		}
+	}
+	if !ok || size != mr.Count() {
+		t.Errorf("Len is %d, %v, read %d bytes", size, ok, mr.Count())
+	}
+}
+
+func TestLen(t *testing.T) {
+	mr := New()
+	mr.WriteFields(map[string]string{"a": "1"})
+	mr.AddFormReader("file", "f.txt", strings.NewReader("content"))
+	size, ok := mr.Len()
+	if !ok {
+		t.Fatal("size is unknown")
+	}
+	body, _ := io.ReadAll(mr)
+	if size != int64(len(body)) {
+		t.Errorf("Len is %d, body is %d bytes", size, len(body))
+	}
+
+	mr = New()
+	mr.AddPart(nil, io.MultiReader(strings.NewReader("x")))
+	if _, ok = mr.Len(); ok {
+		t.Error("size of unknown reader is known")
+	}
+}
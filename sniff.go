@@ -0,0 +1,222 @@
+package multipartreader
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// ErrContentType is returned by ContentPolicy for rejected part content
+var ErrContentType = errors.New("multipartreader: content type rejected")
+
+// ContentCheck is result of comparing declared and sniffed content type of part
+type ContentCheck struct {
+	// Declared is media type from part Content-Type
+	Declared string
+	// Sniffed is media type detected by http.DetectContentType
+	Sniffed string
+	// Type is sniffed media type, zip content is refined by declared type
+	// or extension of zip based format, e.g. docx or jar
+	Type string
+	// Extension is lower-cased extension of filename
+	Extension string
+	// Mismatch lists differences between declared type, sniffed type and extension
+	Mismatch []string
+}
+
+// ContentPolicy validates file parts before their content is stored
+type ContentPolicy struct {
+	// Allowed lists allowed media types of content, e.g. "image/png" or "image/*",
+	// see ContentCheck.Type. Any type is allowed if empty
+	Allowed []string
+	// RejectMismatch rejects parts whose declared type or extension don't match content
+	RejectMismatch bool
+}
+
+// Check sniffs the first bytes of p and compares them with its declared Content-Type
+// and filename extension, extensions are looked up in built-in table so the result
+// doesn't depend on mime.types of the host. Returned reader yields the whole content, sniffed bytes included.
+// Error wraps ErrContentType when policy rejects the part, check is returned anyway
+func (cp *ContentPolicy) Check(p *Part) (io.Reader, *ContentCheck, error) {
+	br := bufio.NewReaderSize(p, 512)
+	head, err := br.Peek(512)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, nil, err
+	}
+
+	check := &ContentCheck{
+		Sniffed:   sniff(head),
+		Extension: strings.ToLower(filepath.Ext(p.FileName())),
+	}
+	check.Declared = mediaType(p.Header.Get("Content-Type"))
+	byExt := extensionTypes[check.Extension]
+
+	check.Type = check.Sniffed
+	if check.Sniffed == "application/zip" {
+		for _, t := range []string{check.Declared, byExt} {
+			if zipContainers[t] {
+				check.Type = t
+				break
+			}
+		}
+	}
+
+	if check.Declared != "" && !sameType(check.Declared, check.Sniffed) {
+		check.Mismatch = append(check.Mismatch, fmt.Sprintf("declared %s, content is %s", check.Declared, check.Sniffed))
+	}
+	if byExt != "" && !sameType(byExt, check.Sniffed) {
+		check.Mismatch = append(check.Mismatch, fmt.Sprintf("extension %s, content is %s", check.Extension, check.Sniffed))
+	}
+
+	if !cp.allowed(check.Type) {
+		return br, check, fmt.Errorf("%w: %s is not allowed", ErrContentType, check.Type)
+	}
+	if cp.RejectMismatch && len(check.Mismatch) > 0 {
+		return br, check, fmt.Errorf("%w: %s", ErrContentType, strings.Join(check.Mismatch, "; "))
+	}
+	return br, check, nil
+}
+
+func (cp *ContentPolicy) allowed(mt string) bool {
+	if len(cp.Allowed) == 0 {
+		return true
+	}
+	for _, a := range cp.Allowed {
+		if a == mt || strings.HasSuffix(a, "/*") && strings.HasPrefix(mt, a[:len(a)-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// executables are signatures of executables http.DetectContentType doesn't know
+var executables = []struct {
+	sig       string
+	mediaType string
+}{
+	{"MZ", "application/x-msdownload"},
+	{"\x7fELF", "application/x-executable"},
+	{"\xfe\xed\xfa\xce", "application/x-mach-binary"},
+	{"\xfe\xed\xfa\xcf", "application/x-mach-binary"},
+	{"\xce\xfa\xed\xfe", "application/x-mach-binary"},
+	{"\xcf\xfa\xed\xfe", "application/x-mach-binary"},
+	{"#!", "text/x-shellscript"},
+}
+
+// sniff detects media type of content by its first bytes
+func sniff(head []byte) string {
+	for _, e := range executables {
+		if strings.HasPrefix(string(head), e.sig) {
+			return e.mediaType
+		}
+	}
+	return mediaType(http.DetectContentType(head))
+}
+
+// mediaType returns media type without parameters, empty if v is invalid
+func mediaType(v string) string {
+	mt, _, err := mime.ParseMediaType(v)
+	if err != nil {
+		return ""
+	}
+	return mt
+}
+
+// sameType reports if declared type agrees with sniffed one, sniffing
+// can't tell text formats apart, reports zip based formats as zip
+// and unknown binary as octet-stream
+func sameType(declared, sniffed string) bool {
+	switch {
+	case declared == sniffed:
+		return true
+	case declared == "application/octet-stream":
+		// client doesn't know the type, it is not a claim about content
+		return true
+	case sniffed == "application/zip":
+		return zipContainers[declared]
+	case sniffed == "text/plain":
+		return strings.HasPrefix(declared, "text/") || strings.HasSuffix(declared, "json") ||
+			strings.HasSuffix(declared, "xml") || declared == "application/javascript"
+	case sniffed == "text/xml":
+		return strings.HasSuffix(declared, "xml")
+	case sniffed == "application/octet-stream":
+		// sniffer knows few binary formats, only executable-looking types are suspicious
+		return !strings.HasPrefix(declared, "image/") && !strings.HasPrefix(declared, "text/")
+	}
+	return false
+}
+
+// zipContainers are formats whose content is zip archive
+var zipContainers = map[string]bool{
+	"application/x-zip-compressed":                                              true,
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   true,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         true,
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": true,
+	"application/vnd.oasis.opendocument.text":                                   true,
+	"application/vnd.oasis.opendocument.spreadsheet":                            true,
+	"application/vnd.oasis.opendocument.presentation":                           true,
+	"application/vnd.oasis.opendocument.graphics":                               true,
+	"application/epub+zip":                                                      true,
+	"application/java-archive":                                                  true,
+	"application/vnd.android.package-archive":                                   true,
+	"application/vnd.ms-xpsdocument":                                            true,
+	"application/vnd.ms-word.document.macroEnabled.12":                          true,
+	"application/vnd.ms-excel.sheet.macroEnabled.12":                            true,
+}
+
+// extensionTypes maps lower-cased extensions to media types,
+// unknown extensions are not checked
+var extensionTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".bmp":  "image/bmp",
+	".ico":  "image/x-icon",
+	".svg":  "image/svg+xml",
+	".pdf":  "application/pdf",
+	".ps":   "application/postscript",
+	".zip":  "application/zip",
+	".gz":   "application/x-gzip",
+	".rar":  "application/x-rar-compressed",
+	".wasm": "application/wasm",
+	".txt":  "text/plain",
+	".csv":  "text/csv",
+	".htm":  "text/html",
+	".html": "text/html",
+	".xml":  "text/xml",
+	".json": "application/json",
+	".js":   "text/javascript",
+	".css":  "text/css",
+	".mp3":  "audio/mpeg",
+	".wav":  "audio/wave",
+	".ogg":  "application/ogg",
+	".mp4":  "video/mp4",
+	".webm": "video/webm",
+	".avi":  "video/avi",
+	".ttf":  "font/ttf",
+	".otf":  "font/otf",
+	".woff": "font/woff",
+	".exe":  "application/x-msdownload",
+	".dll":  "application/x-msdownload",
+	".sh":   "text/x-shellscript",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".docm": "application/vnd.ms-word.document.macroEnabled.12",
+	".xlsm": "application/vnd.ms-excel.sheet.macroEnabled.12",
+	".odt":  "application/vnd.oasis.opendocument.text",
+	".ods":  "application/vnd.oasis.opendocument.spreadsheet",
+	".odp":  "application/vnd.oasis.opendocument.presentation",
+	".odg":  "application/vnd.oasis.opendocument.graphics",
+	".epub": "application/epub+zip",
+	".jar":  "application/java-archive",
+	".apk":  "application/vnd.android.package-archive",
+	".xps":  "application/vnd.ms-xpsdocument",
+}
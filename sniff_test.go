@@ -0,0 +1,90 @@
+package multipartreader
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"testing"
+)
+
+// checkContent runs policy on single file part
+func checkContent(t *testing.T, policy ContentPolicy, filename, contentType string, content []byte) (*ContentCheck, error) {
+	t.Helper()
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", `form-data; name="file"; filename="`+filename+`"`)
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	pw, _ := w.CreatePart(header)
+	pw.Write(content)
+	w.Close()
+
+	d, err := NewDecoder(&buf, w.FormDataContentType(), DecoderOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := d.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, check, err := policy.Check(p)
+	if r != nil {
+		got, rerr := io.ReadAll(r)
+		if rerr != nil || !bytes.Equal(got, content) {
+			t.Fatalf("content after Check is %q, %v", got, rerr)
+		}
+	}
+	return check, err
+}
+
+func TestContentPolicy(t *testing.T) {
+	const (
+		docx = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+		jar  = "application/java-archive"
+	)
+	zip := []byte("PK\x03\x04\x14\x00\x06\x00\x08\x00\x00\x00!\x00[Content_Types].xml")
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	exe := []byte("MZ\x90\x00\x03\x00\x00\x00")
+	strict := ContentPolicy{RejectMismatch: true}
+
+	tests := []struct {
+		name        string
+		policy      ContentPolicy
+		filename    string
+		contentType string
+		content     []byte
+		typ         string
+		rejected    bool
+	}{
+		{"docx", strict, "report.docx", docx, zip, docx, false},
+		{"docx by extension", strict, "report.docx", "application/octet-stream", zip, docx, false},
+		{"jar", strict, "lib.jar", jar, zip, jar, false},
+		{"allowed docx", ContentPolicy{Allowed: []string{docx}}, "report.docx", docx, zip, docx, false},
+		{"docx is not jar", ContentPolicy{Allowed: []string{jar}}, "report.docx", docx, zip, docx, true},
+		{"zip declared as png", strict, "image.png", "image/png", zip, "application/zip", true},
+		{"png", ContentPolicy{Allowed: []string{"image/*"}, RejectMismatch: true}, "image.png", "image/png", png, "image/png", false},
+		{"png with jpg extension", strict, "image.jpg", "", png, "image/png", true},
+		{"exe declared as png", strict, "image.png", "image/png", exe, "application/x-msdownload", true},
+		{"exe not allowed", ContentPolicy{Allowed: []string{"image/*"}}, "setup", "", exe, "application/x-msdownload", true},
+		{"unknown extension", strict, "data.unknownext", "", png, "image/png", false},
+		{"mismatch allowed", ContentPolicy{}, "image.png", "image/png", zip, "application/zip", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check, err := checkContent(t, tt.policy, tt.filename, tt.contentType, tt.content)
+			if check == nil {
+				t.Fatalf("no check: %v", err)
+			}
+			if check.Type != tt.typ {
+				t.Errorf("type is %s, want %s", check.Type, tt.typ)
+			}
+			if rejected := errors.Is(err, ErrContentType); rejected != tt.rejected {
+				t.Errorf("rejected is %v, want %v: %v, %+v", rejected, tt.rejected, err, check)
+			}
+		})
+	}
+}
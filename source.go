@@ -0,0 +1,75 @@
+package multipartreader
+
+import (
+	"io"
+	"net/textproto"
+	"os"
+	"time"
+)
+
+// fileSource is part body which opens file on first Read and closes it at EOF,
+// so files are not opened until the body is actually sent
+type fileSource struct {
+	path    string
+	size    int64
+	modTime time.Time
+
+	f   *os.File
+	pos int64
+}
+
+// newFileSource creates fileSource, file is checked to exist but not opened
+func newFileSource(path string) (fs *fileSource, err error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	return &fileSource{path: path, size: fi.Size(), modTime: fi.ModTime()}, nil
+}
+
+func (fs *fileSource) open() (err error) {
+	if fs.f != nil {
+		return
+	}
+	if fs.f, err = os.Open(fs.path); err != nil {
+		return
+	}
+	if fs.pos > 0 {
+		_, err = fs.f.Seek(fs.pos, io.SeekStart)
+	}
+	return
+}
+
+func (fs *fileSource) Read(p []byte) (n int, err error) {
+	if err = fs.open(); err != nil {
+		return
+	}
+	n, err = fs.f.Read(p)
+	fs.pos += int64(n)
+	if err == io.EOF {
+		fs.f.Close()
+	}
+	return
+}
+
+// Seek moves position without opening the file
+func (fs *fileSource) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+		fs.pos = offset
+	case io.SeekCurrent:
+		fs.pos += offset
+	case io.SeekEnd:
+		fs.pos = fs.size + offset
+	}
+	if fs.f != nil {
+		return fs.f.Seek(fs.pos, io.SeekStart)
+	}
+	return fs.pos, nil
+}
+
+// AddLazyPart adds new part whose body is opened by open on first Read,
+// e.g. after server accepted Expect: 100-continue
+func (mr *MultipartReader) AddLazyPart(header textproto.MIMEHeader, open func() (io.Reader, error), opts ...PartOption) {
+	mr.AddPart(header, &lazyReader{open: open}, opts...)
+}
@@ -0,0 +1,133 @@
+package multipartreader
+
+import (
+	"sync"
+	"time"
+)
+
+// ewmaAlpha is weight of the latest throughput sample
+const ewmaAlpha = 0.3
+
+// ewmaWindow is minimal interval between throughput samples
+const ewmaWindow = 200 * time.Millisecond
+
+// Stats is snapshot of upload progress returned by MultipartReader.Stats
+type Stats struct {
+	BytesRead int64
+	// BytesTotal is -1 if body length is unknown
+	BytesTotal int64
+	Elapsed    time.Duration
+	// Throughput is smoothed (EWMA) rate in bytes per second
+	Throughput float64
+	// ETA is -1 if it can not be estimated
+	ETA time.Duration
+	// Current is part being read, nil if none
+	Current   *PartInfo
+	Completed []PartInfo
+}
+
+// stats is state behind Stats, guarded by mu as Stats is polled
+// from other goroutines
+type stats struct {
+	mu        sync.Mutex
+	start     time.Time
+	end       time.Time
+	total     int64
+	read      int64
+	current   *part
+	completed []PartInfo
+
+	sampleAt   time.Time
+	sampleRead int64
+	throughput float64
+}
+
+// bodyStart calls length under the lock, Len seeks readers
+// and Stats calls it too until the body is started
+func (s *stats) bodyStart(length func() (int64, bool)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.start = time.Now()
+	s.sampleAt = s.start
+	s.total, _ = length()
+}
+
+func (s *stats) bytesRead(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.read += int64(n)
+	now := time.Now()
+	if dt := now.Sub(s.sampleAt); dt >= ewmaWindow {
+		rate := float64(s.read-s.sampleRead) / dt.Seconds()
+		if s.throughput == 0 {
+			s.throughput = rate
+		} else {
+			s.throughput = ewmaAlpha*rate + (1-ewmaAlpha)*s.throughput
+		}
+		s.sampleAt = now
+		s.sampleRead = s.read
+	}
+}
+
+func (s *stats) partStart(p *part) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current = p
+}
+
+func (s *stats) partFinish(p *part) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current = nil
+	s.completed = append(s.completed, p.info())
+}
+
+func (s *stats) bodyFinish() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.end = time.Now()
+}
+
+// Stats returns snapshot of upload progress, safe to call while reading
+func (mr *MultipartReader) Stats() Stats {
+	s := &mr.stats
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := Stats{
+		BytesRead:  s.read,
+		BytesTotal: s.total,
+		Throughput: s.throughput,
+		ETA:        -1,
+		Completed:  append([]PartInfo(nil), s.completed...),
+	}
+	if s.start.IsZero() {
+		st.BytesTotal, _ = mr.lenOrUnknown()
+		return st
+	}
+	if s.end.IsZero() {
+		st.Elapsed = time.Since(s.start)
+	} else {
+		st.Elapsed = s.end.Sub(s.start)
+	}
+	if st.Throughput == 0 && st.Elapsed > 0 {
+		st.Throughput = float64(s.read) / st.Elapsed.Seconds()
+	}
+	if s.current != nil {
+		info := s.current.info()
+		st.Current = &info
+	}
+	if st.BytesTotal >= 0 && st.Throughput > 0 {
+		st.ETA = time.Duration(float64(st.BytesTotal-st.BytesRead) / st.Throughput * float64(time.Second))
+	}
+	return st
+}
+
+// lenOrUnknown returns Len or -1 if it is unknown
+func (mr *MultipartReader) lenOrUnknown() (int64, bool) {
+	n, ok := mr.Len()
+	if !ok {
+		return -1, false
+	}
+	return n, true
+}
@@ -0,0 +1,97 @@
+package multipartreader
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pacedReaderAt pauses before each ReadAt
+type pacedReaderAt struct {
+	r io.ReaderAt
+}
+
+func (pr *pacedReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	time.Sleep(2 * time.Millisecond)
+	return pr.r.ReadAt(p, off)
+}
+
+func TestStatsDuringUpload(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+	}))
+	defer srv.Close()
+
+	content := strings.Repeat("x", 1<<20)
+	mr := New()
+	mr.WriteFields(map[string]string{"a": "1"})
+	// size of section is known to Len
+	mr.AddFormReader("file", "f.bin", io.NewSectionReader(&pacedReaderAt{r: strings.NewReader(content)}, 0, int64(len(content))))
+	total, ok := mr.Len()
+	if !ok {
+		t.Fatal("length of body is unknown")
+	}
+	if st := mr.Stats(); st.BytesRead != 0 || st.BytesTotal != total || st.ETA != -1 {
+		t.Errorf("stats before upload: %+v", st)
+	}
+
+	done := make(chan struct{})
+	polled := make(chan []Stats)
+	go func() {
+		var snapshots []Stats
+		for {
+			select {
+			case <-done:
+				polled <- snapshots
+				return
+			default:
+			}
+			snapshots = append(snapshots, mr.Stats())
+			time.Sleep(100 * time.Microsecond)
+		}
+	}()
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := mr.Upload(nil, req)
+	close(done)
+	snapshots := <-polled
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	var last int64
+	inProgress := false
+	for _, st := range snapshots {
+		if st.BytesRead < last {
+			t.Fatalf("BytesRead went back from %d to %d", last, st.BytesRead)
+		}
+		last = st.BytesRead
+		if st.BytesRead > 0 && st.BytesRead < total {
+			inProgress = true
+			if st.Elapsed <= 0 || st.ETA < 0 || st.Current == nil {
+				t.Errorf("stats in progress: %+v", st)
+			}
+		}
+	}
+	if !inProgress {
+		t.Errorf("no stats polled in progress out of %d", len(snapshots))
+	}
+
+	st := mr.Stats()
+	if st.BytesRead != total || st.BytesRead != mr.Count() || st.BytesTotal != total {
+		t.Errorf("stats after upload: read %d of %d, Count is %d", st.BytesRead, st.BytesTotal, mr.Count())
+	}
+	if st.Elapsed <= 0 || st.Current != nil || len(st.Completed) != 2 {
+		t.Errorf("stats after upload: %+v", st)
+	}
+	if st.Completed[1].Size != int64(len(content)) || st.Completed[1].Duration <= 0 {
+		t.Errorf("completed file part: %+v", st.Completed[1])
+	}
+}
@@ -0,0 +1,71 @@
+package multipartreader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// WithTee copies part content into w as it is read,
+// wire bytes and Count are not affected
+func WithTee(w io.Writer) PartOption {
+	return func(p *part) {
+		p.tees = append(p.tees, w)
+	}
+}
+
+// WithCache stores part content in dir as it is read, file is named
+// by SHA-256 of the content. done, if not nil, is called with path of the stored file
+func WithCache(dir string, done func(path string)) PartOption {
+	return func(p *part) {
+		c := &cacheWriter{dir: dir, hash: sha256.New()}
+		p.tees = append(p.tees, c)
+		p.finish = append(p.finish, func(err error) error {
+			path, cerr := c.close(err)
+			if cerr == nil && err == nil && done != nil {
+				done(path)
+			}
+			return cerr
+		})
+	}
+}
+
+// cacheWriter writes into temporary file which is renamed after its hash on close
+type cacheWriter struct {
+	dir  string
+	hash hash.Hash
+	file *os.File
+}
+
+func (c *cacheWriter) Write(p []byte) (n int, err error) {
+	if c.file == nil {
+		if c.file, err = os.CreateTemp(c.dir, ".part-*"); err != nil {
+			return
+		}
+	}
+	c.hash.Write(p)
+	return c.file.Write(p)
+}
+
+// close finishes the cache entry, temporary file is removed if reading failed
+func (c *cacheWriter) close(readErr error) (path string, err error) {
+	if c.file == nil {
+		// empty content
+		if c.file, err = os.CreateTemp(c.dir, ".part-*"); err != nil {
+			return
+		}
+	}
+	tmp := c.file.Name()
+	if err = c.file.Close(); err != nil || readErr != nil {
+		os.Remove(tmp)
+		return
+	}
+	path = filepath.Join(c.dir, hex.EncodeToString(c.hash.Sum(nil)))
+	if err = os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+	}
+	return
+}
@@ -0,0 +1,94 @@
+package multipartreader
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// readWithOption reads body of a single part with opts
+func readWithOption(t *testing.T, content io.Reader, opts ...PartOption) (body []byte, count int64, err error) {
+	t.Helper()
+	mr := New()
+	if err := mr.SetBoundary("tee"); err != nil {
+		t.Fatal(err)
+	}
+	mr.AddPart(textproto.MIMEHeader{"Content-Type": {"text/plain"}}, content, opts...)
+	body, err = io.ReadAll(mr)
+	return body, mr.Count(), err
+}
+
+func TestWithTee(t *testing.T) {
+	content := strings.Repeat("tee content\r\n", 10000)
+	want, wantCount, err := readWithOption(t, strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tee bytes.Buffer
+	body, count, err := readWithOption(t, iotest.HalfReader(strings.NewReader(content)), WithTee(&tee))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(body, want) || count != wantCount {
+		t.Errorf("body with tee differs, got %d bytes, Count %d, want %d, %d", len(body), count, len(want), wantCount)
+	}
+	if tee.String() != content {
+		t.Errorf("tee got %d bytes, want %d", tee.Len(), len(content))
+	}
+}
+
+func TestWithCache(t *testing.T) {
+	for _, content := range []string{"", "cached content", strings.Repeat("x", 100000)} {
+		want, _, err := readWithOption(t, strings.NewReader(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dir := t.TempDir()
+		var path string
+		body, _, err := readWithOption(t, strings.NewReader(content), WithCache(dir, func(p string) { path = p }))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(body, want) {
+			t.Errorf("%d bytes: body with cache differs", len(content))
+		}
+		sum := sha256.Sum256([]byte(content))
+		if want := filepath.Join(dir, hex.EncodeToString(sum[:])); path != want {
+			t.Errorf("%d bytes: cached as %q, want %q", len(content), path, want)
+		}
+		cached, err := os.ReadFile(path)
+		if err != nil || string(cached) != content {
+			t.Errorf("%d bytes: cache file has %d bytes, %v", len(content), len(cached), err)
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+			t.Errorf("%d bytes: cache dir has %d entries, want 1", len(content), len(entries))
+		}
+	}
+}
+
+func TestWithCacheReadError(t *testing.T) {
+	errRead := errors.New("read failed")
+	dir := t.TempDir()
+	called := false
+	content := io.MultiReader(strings.NewReader("partial content"), iotest.ErrReader(errRead))
+	_, _, err := readWithOption(t, content, WithCache(dir, func(string) { called = true }))
+	if !errors.Is(err, errRead) {
+		t.Fatalf("got %v, want read error", err)
+	}
+	if called {
+		t.Errorf("done called for failed part")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("cache dir has %d entries after failed read, want none", len(entries))
+	}
+}
@@ -0,0 +1,34 @@
+package multipartreader
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Upload sends req with body of MultipartReader, see SetupRequest.
+// If the server rejects Expect: 100-continue with 417 before the body was sent,
+// the request is sent again without Expect header, 417 after any body byte was read is returned as is
+func (mr *MultipartReader) Upload(client *http.Client, req *http.Request, opts ...RequestOption) (resp *http.Response, err error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	mr.SetupRequest(req, opts...)
+	resp, err = client.Do(req)
+	mr.logResult("multipart upload", req, resp, err)
+	if err != nil || resp.StatusCode != http.StatusExpectationFailed || req.Header.Get("Expect") == "" {
+		return
+	}
+	if mr.Count() > 0 {
+		// body was already sent, it can't be read again
+		return
+	}
+	resp.Body.Close()
+
+	mr.retried(1, errors.New(resp.Status))
+	retry := req.Clone(req.Context())
+	retry.Header.Del("Expect")
+	retry.Body = mr.GetCloseReader()
+	resp, err = client.Do(retry)
+	mr.logResult("multipart upload", retry, resp, err)
+	return
+}
@@ -0,0 +1,126 @@
+package multipartreader
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newLazyUpload returns body whose file part is opened on first Read, opened is set then
+func newLazyUpload(t *testing.T, opened *atomic.Bool) *MultipartReader {
+	t.Helper()
+	mr := New()
+	if err := mr.SetBoundary("upload"); err != nil {
+		t.Fatal(err)
+	}
+	mr.WriteFields(map[string]string{"a": "1"})
+	header := textproto.MIMEHeader{"Content-Disposition": {`form-data; name="file"; filename="f.txt"`}}
+	mr.AddLazyPart(header, func() (io.Reader, error) {
+		opened.Store(true)
+		return strings.NewReader(strings.Repeat("lazy content\r\n", 1000)), nil
+	})
+	return mr
+}
+
+// uploadServer records bodies of requests, reject decides status of request
+// before and after its body is read
+type uploadServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	bodies [][]byte
+	// openedEarly is set when lazy part was opened before 100 Continue was sent
+	openedEarly bool
+}
+
+func newUploadServer(t *testing.T, opened *atomic.Bool, reject func(r *http.Request, read bool) bool) *uploadServer {
+	s := &uploadServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if r.Header.Get("Expect") != "" && opened.Load() {
+			s.openedEarly = true
+		}
+		if reject(r, false) {
+			s.bodies = append(s.bodies, nil)
+			w.WriteHeader(http.StatusExpectationFailed)
+			return
+		}
+		// server sends 100 Continue on the first read
+		body, _ := io.ReadAll(r.Body)
+		s.bodies = append(s.bodies, body)
+		if reject(r, true) {
+			w.WriteHeader(http.StatusExpectationFailed)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// continueClient waits for 100 Continue before sending body
+func continueClient() *http.Client {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.ExpectContinueTimeout = 10 * time.Second
+	return &http.Client{Transport: tr}
+}
+
+func TestUploadExpectContinue(t *testing.T) {
+	var opened atomic.Bool
+	want, err := io.ReadAll(newLazyUpload(t, &opened))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		reject   func(r *http.Request, read bool) bool
+		status   int
+		attempts int
+	}{
+		{"accepted", func(r *http.Request, read bool) bool { return false }, http.StatusOK, 1},
+		{"rejected before body", func(r *http.Request, read bool) bool {
+			return !read && r.Header.Get("Expect") != ""
+		}, http.StatusOK, 2},
+		{"rejected after body", func(r *http.Request, read bool) bool { return read }, http.StatusExpectationFailed, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opened atomic.Bool
+			srv := newUploadServer(t, &opened, tt.reject)
+			mr := newLazyUpload(t, &opened)
+			req, err := http.NewRequest(http.MethodPost, srv.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := mr.Upload(continueClient(), req, WithExpectContinue())
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.status {
+				t.Errorf("got status %d, want %d", resp.StatusCode, tt.status)
+			}
+			srv.mu.Lock()
+			defer srv.mu.Unlock()
+			if srv.openedEarly {
+				t.Errorf("lazy part was opened before 100 Continue")
+			}
+			if len(srv.bodies) != tt.attempts {
+				t.Fatalf("server got %d requests, want %d", len(srv.bodies), tt.attempts)
+			}
+			if got := srv.bodies[len(srv.bodies)-1]; !bytes.Equal(got, want) {
+				t.Errorf("server got %d bytes, want %d", len(got), len(want))
+			}
+			if mr.Count() != int64(len(want)) {
+				t.Errorf("Count is %d, want %d", mr.Count(), len(want))
+			}
+		})
+	}
+}